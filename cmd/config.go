@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/maddevsio/ariadna/config"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect the configuration",
+}
+
+var configCheckCmd = &cobra.Command{
+	Use:   "check",
+	Short: "Validate the configuration and report every invalid key",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if _, err := config.Load(configFile); err != nil {
+			return err
+		}
+		source := viper.ConfigFileUsed()
+		if source == "" {
+			source = "environment"
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "%s: ok\n", source)
+		return nil
+	},
+}
+
+func init() {
+	configCmd.AddCommand(configCheckCmd)
+	rootCmd.AddCommand(configCmd)
+}
@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"context"
+
+	"github.com/maddevsio/ariadna/export"
+	"github.com/maddevsio/ariadna/logging"
+	"github.com/maddevsio/ariadna/metrics"
+	"github.com/maddevsio/ariadna/osm"
+	"github.com/maddevsio/ariadna/store"
+	"github.com/spf13/cobra"
+)
+
+var importCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Download the extract and import it into a new index",
+	Long: `Download the extract and import it into a new index generation. The
+alias is pointed to it only after a successful import, previous
+generations except the last keep_indices are deleted.
+
+With --export the documents are written to a file as ndjson, geojson or
+csv instead, the store is not touched. Such a file is loaded later with
+import-file.
+
+Progress is logged every progress_interval. With --report a JSON report
+of document counts per layer, stage timings and errors is written when
+the import ends, successfully or not.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, logger, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		if err := c.ValidateImport(true); err != nil {
+			return err
+		}
+		ctx, cancel := signalContext(logger)
+		defer cancel()
+		serveMetrics(ctx, c, metrics.Import, logger)
+		var sink store.Sink
+		if exportPath != "" {
+			if sink, err = export.New(exportPath, exportFormat); err != nil {
+				return err
+			}
+		} else {
+			s, err := newStore(c, logger)
+			if err != nil {
+				return err
+			}
+			defer s.Close()
+			sink = s
+		}
+		i, err := osm.NewImporter(c, sink, logger)
+		if err != nil {
+			return err
+		}
+		err = runImport(ctx, i)
+		report := i.Report(err)
+		if c.ReportFile != "" {
+			if werr := report.WriteFile(c.ReportFile); werr != nil {
+				logger.Errorf("could not write report: %v", werr)
+			} else {
+				logger.WithField(logging.RunField, report.Run).Infof("report written to %s", c.ReportFile)
+			}
+		}
+		pushMetrics(c, "ariadna_import", metrics.Import, logger)
+		return err
+	},
+}
+
+func runImport(ctx context.Context, i *osm.Importer) error {
+	if err := i.Start(ctx); err != nil {
+		return err
+	}
+	if err := i.WaitStop(); err != nil {
+		return err
+	}
+	return i.Done()
+}
+
+var (
+	exportPath   string
+	exportFormat string
+)
+
+func init() {
+	flags := importCmd.Flags()
+	flags.StringVar(&exportPath, "export", "", "write documents to the file instead of the store")
+	flags.StringVar(&exportFormat, "format", "", "ndjson, geojson or csv (default by the file extension)")
+	flags.String("report", "", "write a JSON report of the import to the file (default report_file)")
+	bindFlags(flags.Lookup, map[string]string{"report_file": "report"})
+	rootCmd.AddCommand(importCmd)
+}
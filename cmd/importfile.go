@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"context"
+	"os"
+
+	"github.com/maddevsio/ariadna/export"
+	"github.com/maddevsio/ariadna/logging"
+	"github.com/maddevsio/ariadna/metrics"
+	"github.com/maddevsio/ariadna/store"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var importFileCmd = &cobra.Command{
+	Use:   "import-file FILE",
+	Short: "Load an exported file into a new index",
+	Long: `Load documents written by import --export into a new index
+generation without parsing the extract again. The format is guessed by
+the file extension unless --format is given. The alias is pointed to the
+new generation once all documents are written, previous generations
+except the last keep_indices are deleted.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, logger, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		format, err := export.FormatOf(args[0], importFileFormat)
+		if err != nil {
+			return err
+		}
+		f, err := os.Open(args[0])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		ctx, cancel := signalContext(logger)
+		defer cancel()
+		serveMetrics(ctx, c, metrics.Import, logger)
+		defer pushMetrics(c, "ariadna_import_file", metrics.Import, logger)
+		s, err := newStore(c, logger)
+		if err != nil {
+			return err
+		}
+		defer s.Close()
+		log := logger.WithField(logging.RunField, logging.NewID())
+		ctx = logging.NewContext(ctx, log)
+		err = loadFile(ctx, s, f, format, c.KeepIndices, log)
+		metrics.ImportDone(err)
+		return err
+	},
+}
+
+// loadFile loads the file into a new generation and deletes old ones
+func loadFile(ctx context.Context, s store.Store, f *os.File, format string, keep int, log logrus.FieldLogger) error {
+	count, err := export.Load(ctx, s, f, format)
+	if err != nil {
+		return err
+	}
+	log.Infof("loaded %d documents from %s", count, f.Name())
+	deleted, err := s.Prune(ctx, keep)
+	if err != nil {
+		return err
+	}
+	for _, name := range deleted {
+		log.Infof("deleted index %s", name)
+	}
+	return nil
+}
+
+var importFileFormat string
+
+func init() {
+	importFileCmd.Flags().StringVar(&importFileFormat, "format", "", "ndjson, geojson or csv (default by the file extension)")
+	rootCmd.AddCommand(importFileCmd)
+}
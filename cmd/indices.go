@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var indicesCmd = &cobra.Command{
+	Use:   "indices",
+	Short: "Manage index generations created by imports",
+}
+
+var indicesListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List index generations, the one behind the alias is marked with *",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		s, err := openStore()
+		if err != nil {
+			return err
+		}
+		defer s.Close()
+		indices, err := s.Indices(context.Background())
+		if err != nil {
+			return err
+		}
+		for _, index := range indices {
+			mark := " "
+			if index.Aliased {
+				mark = "*"
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "%s %s\n", mark, index.Name)
+		}
+		return nil
+	},
+}
+
+var indicesRollbackCmd = &cobra.Command{
+	Use:   "rollback",
+	Short: "Point the alias to the previous index generation",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		s, err := openStore()
+		if err != nil {
+			return err
+		}
+		defer s.Close()
+		name, err := s.Rollback(context.Background())
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "alias points to %s\n", name)
+		return nil
+	},
+}
+
+var indicesPruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Delete index generations the alias does not point to",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, logger, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		s, err := newStore(c, logger)
+		if err != nil {
+			return err
+		}
+		defer s.Close()
+		deleted, err := s.Prune(context.Background(), c.KeepIndices)
+		if err != nil {
+			return err
+		}
+		for _, name := range deleted {
+			fmt.Fprintf(cmd.OutOrStdout(), "deleted %s\n", name)
+		}
+		return nil
+	},
+}
+
+func init() {
+	indicesPruneCmd.Flags().Int("keep", 0, "number of previous generations to keep (default keep_indices)")
+	bindFlags(indicesPruneCmd.Flags().Lookup, map[string]string{"keep_indices": "keep"})
+	indicesCmd.AddCommand(indicesListCmd, indicesRollbackCmd, indicesPruneCmd)
+	rootCmd.AddCommand(indicesCmd)
+}
@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/maddevsio/ariadna/model"
+	"github.com/spf13/cobra"
+)
+
+var queryCmd = &cobra.Command{
+	Use:   "query [TEXT]",
+	Short: "Search the index from the command line",
+	Long: `Search addresses by text, or nearest addresses with --lat and --lon,
+and print the results as JSON.`,
+	Example: `  ariadna query "Чуй 120"
+  ariadna query --lat 42.87 --lon 74.59`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		reverse := cmd.Flags().Changed("lat") || cmd.Flags().Changed("lon")
+		if reverse == (len(args) > 0) {
+			return errors.New("either query text or --lat and --lon must be given")
+		}
+		s, err := openStore()
+		if err != nil {
+			return err
+		}
+		defer s.Close()
+		var addresses []model.Address
+		if reverse {
+			addresses, err = s.Reverse(context.Background(), queryLat, queryLon)
+		} else {
+			addresses, err = s.Search(context.Background(), strings.Join(args, " "))
+		}
+		if err != nil {
+			return err
+		}
+		enc := json.NewEncoder(cmd.OutOrStdout())
+		enc.SetIndent("", "  ")
+		return enc.Encode(addresses)
+	},
+}
+
+var queryLat, queryLon float64
+
+func init() {
+	queryCmd.Flags().Float64Var(&queryLat, "lat", 0, "latitude for reverse geocoding")
+	queryCmd.Flags().Float64Var(&queryLon, "lon", 0, "longitude for reverse geocoding")
+	rootCmd.AddCommand(queryCmd)
+}
@@ -0,0 +1,160 @@
+// Package cmd implements the ariadna command line interface
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/maddevsio/ariadna/config"
+	"github.com/maddevsio/ariadna/elastic"
+	"github.com/maddevsio/ariadna/embedded"
+	"github.com/maddevsio/ariadna/logging"
+	"github.com/maddevsio/ariadna/metrics"
+	"github.com/maddevsio/ariadna/store"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+var rootCmd = &cobra.Command{
+	Use:   "ariadna",
+	Short: "Geocoder for OpenStreetMap data built on top of Elasticsearch",
+	Long: `Ariadna imports OpenStreetMap extracts into Elasticsearch, OpenSearch
+or an embedded index on local disk and serves forward and reverse
+geocoding over HTTP.
+
+Settings are read from ariadna.yml in the current or parent directory
+or the file given with --config. ARIADNA_* environment variables override
+the file, flags override both.`,
+	SilenceUsage: true,
+}
+
+// configFile is an explicit config path, the file is searched for if empty
+var configFile string
+
+func init() {
+	flags := rootCmd.PersistentFlags()
+	flags.StringVar(&configFile, "config", "", "config file (default ariadna.yml in . or ..)")
+	flags.String("store", "", "elastic or embedded (default store)")
+	flags.String("data-dir", "", "directory of the embedded index (default data_dir)")
+	flags.String("elastic-index", "", "alias of the elasticsearch index")
+	flags.StringSlice("elastic-urls", nil, "elasticsearch addresses")
+	flags.String("osm-url", "", "download url of the osm.pbf extract")
+	flags.String("osm-filename", "", "path of the osm.pbf extract")
+	flags.String("import-country", "", "name of the country to import")
+	flags.Bool("two-pass", false, "read the file twice keeping only referenced nodes")
+	flags.String("log-level", "", "debug, info, warn or error (default log.level)")
+	flags.String("log-format", "", "text or json (default log.format)")
+	bindFlags(flags.Lookup, map[string]string{
+		"store":          "store",
+		"data_dir":       "data-dir",
+		"elastic_index":  "elastic-index",
+		"elastic_urls":   "elastic-urls",
+		"osm_url":        "osm-url",
+		"osm_filename":   "osm-filename",
+		"import_country": "import-country",
+		"two_pass":       "two-pass",
+		"log.level":      "log-level",
+		"log.format":     "log-format",
+	})
+}
+
+// Execute runs the command given in os.Args
+func Execute() error {
+	return rootCmd.Execute()
+}
+
+// bindFlags makes flags override config keys, keys maps config keys to
+// flag names
+func bindFlags(lookup func(string) *pflag.Flag, keys map[string]string) {
+	for key, name := range keys {
+		if err := viper.BindPFlag(key, lookup(name)); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// loadConfig loads the config and builds the logger it configures, the
+// logger is passed to every component
+func loadConfig() (*config.Ariadna, *logrus.Logger, error) {
+	c, err := config.Load(configFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	logger, err := logging.New(c.Log)
+	if err != nil {
+		return nil, nil, fmt.Errorf("log.output: %v", err)
+	}
+	return c, logger, nil
+}
+
+// newStore opens the store selected by the config
+func newStore(c *config.Ariadna, logger *logrus.Logger) (store.Store, error) {
+	if c.Store == config.StoreEmbedded {
+		s, err := embedded.New(c, logger)
+		if err != nil {
+			return nil, err
+		}
+		return s, nil
+	}
+	e, err := elastic.New(c, logger)
+	if err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// openStore loads the config and opens the store
+func openStore() (store.Store, error) {
+	c, logger, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	return newStore(c, logger)
+}
+
+// serveMetrics serves metrics gathered by g on metrics.listen until ctx is
+// canceled
+func serveMetrics(ctx context.Context, c *config.Ariadna, g prometheus.Gatherer, logger *logrus.Logger) {
+	if c.Metrics.Listen == "" {
+		return
+	}
+	go func() {
+		if err := metrics.Serve(ctx, c.Metrics.Listen, g); err != nil {
+			logger.Errorf("metrics: %v", err)
+		}
+	}()
+}
+
+// pushMetrics pushes metrics gathered by g to metrics.pushgateway as the
+// job
+func pushMetrics(c *config.Ariadna, job string, g prometheus.Gatherer, logger *logrus.Logger) {
+	if c.Metrics.PushGateway == "" {
+		return
+	}
+	if err := metrics.Push(c.Metrics.PushGateway, job, g); err != nil {
+		logger.Warnf("could not push metrics: %v", err)
+	}
+}
+
+// signalContext returns a context canceled on SIGINT or SIGTERM
+func signalContext(logger *logrus.Logger) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		select {
+		case s := <-signals:
+			logger.Infof("received %s, stopping", s)
+			cancel()
+		case <-ctx.Done():
+		}
+		signal.Stop(signals)
+	}()
+	return ctx, cancel
+}
@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"github.com/maddevsio/ariadna/osm"
+	"github.com/spf13/cobra"
+)
+
+var serveCmd = &cobra.Command{
+	Use:     "serve",
+	Aliases: []string{"web"},
+	Short:   "Serve the geocoding API",
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, logger, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		ctx, cancel := signalContext(logger)
+		defer cancel()
+		s, err := newStore(c, logger)
+		if err != nil {
+			return err
+		}
+		defer s.Close()
+		i, err := osm.NewImporter(c, s, logger)
+		if err != nil {
+			return err
+		}
+		return i.StartWebServer(ctx)
+	},
+}
+
+func init() {
+	serveCmd.Flags().String("listen", "", "address to listen on (default server.listen)")
+	bindFlags(serveCmd.Flags().Lookup, map[string]string{"server.listen": "listen"})
+	rootCmd.AddCommand(serveCmd)
+}
@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"github.com/maddevsio/ariadna/osm"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/spf13/cobra"
+)
+
+var updateCmd = &cobra.Command{
+	Use:   "update --diff FILE",
+	Short: "Apply an OSM change file to the current index",
+	Long: `Apply an OSM change file (.osc or .osc.gz) to the index behind the
+alias. The extract at osm_filename is read to locate changed elements, so
+it must be the one the index was built from. Changed relations and
+crossroads are updated by the next full import only.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, logger, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		if err := c.ValidateImport(false); err != nil {
+			return err
+		}
+		ctx, cancel := signalContext(logger)
+		defer cancel()
+		serveMetrics(ctx, c, prometheus.DefaultGatherer, logger)
+		defer pushMetrics(c, "ariadna_update", prometheus.DefaultGatherer, logger)
+		s, err := newStore(c, logger)
+		if err != nil {
+			return err
+		}
+		defer s.Close()
+		i, err := osm.NewImporter(c, s, logger)
+		if err != nil {
+			return err
+		}
+		return i.Update(ctx, diffPath)
+	},
+}
+
+var diffPath string
+
+func init() {
+	updateCmd.Flags().StringVar(&diffPath, "diff", "", "path of the change file")
+	updateCmd.MarkFlagRequired("diff")
+	rootCmd.AddCommand(updateCmd)
+}
@@ -1,34 +1,120 @@
 package config
 
-import "github.com/spf13/viper"
+import (
+	"time"
+
+	"github.com/spf13/viper"
+)
 
 type Ariadna struct {
+	// Store is elastic to use an elasticsearch or opensearch cluster or
+	// embedded to keep the index in DataDir
+	Store   string `json:"store" mapstructure:"store"`
+	DataDir string `json:"data_dir" mapstructure:"data_dir"`
+	// ElasticIndex is the alias of index generations, the embedded store
+	// uses it as a name too
 	ElasticIndex  string   `json:"elastic_index" mapstructure:"elastic_index"`
 	ElasticURLs   []string `json:"elastic_urls" mapstructure:"elastic_urls"`
 	OSMFilename   string   `json:"osm_filename" mapstructure:"osm_filename"`
 	IndexSettings string   `json:"index_settings" mapstructure:"index_settings"`
 	OSMURL        string   `json:"osm_url" mapstructure:"osm_url"`
 	ImportCountry string   `json:"import_country" mapstructure:"import_country"`
+	StoredTags    []string `json:"stored_tags" mapstructure:"stored_tags"`
+	// TwoPass reads the file twice to keep only referenced nodes in memory
+	TwoPass bool `json:"two_pass" mapstructure:"two_pass"`
+	// CrossroadClusterDistance is a distance in meters within which
+	// intersection nodes of the same streets make up a single crossroad
+	CrossroadClusterDistance float64 `json:"crossroad_cluster_distance" mapstructure:"crossroad_cluster_distance"`
+	// KeepIndices is the number of previous index generations kept for
+	// rollback after a successful import
+	KeepIndices int `json:"keep_indices" mapstructure:"keep_indices"`
+	// BoundariesFile is a path a successful import writes country, city and
+	// district polygons to as GeoJSON once its index is served, the API
+	// serves it at /api/boundaries. Nothing is written when empty.
+	BoundariesFile string `json:"boundaries_file" mapstructure:"boundaries_file"`
+	// ProgressInterval is how often the import logs its progress, 0
+	// disables progress logging
+	ProgressInterval time.Duration `json:"progress_interval" mapstructure:"progress_interval"`
+	// ReportFile is a path the import writes its JSON report to
+	ReportFile string `json:"report_file" mapstructure:"report_file"`
+	// Elasticsearch credentials. ElasticAPIKey is the base64 encoded
+	// "id:api_key" pair, it is used instead of the username and password.
+	ElasticUsername string `json:"elastic_username" mapstructure:"elastic_username"`
+	ElasticPassword string `json:"elastic_password" mapstructure:"elastic_password"`
+	ElasticAPIKey   string `json:"elastic_api_key" mapstructure:"elastic_api_key"`
+	// ElasticCloudID is the Elastic Cloud deployment id, it replaces ElasticURLs
+	ElasticCloudID string `json:"elastic_cloud_id" mapstructure:"elastic_cloud_id"`
+	// ElasticCACert is a path of a PEM file with CA certificates to trust
+	// in addition to the system ones
+	ElasticCACert             string `json:"elastic_ca_cert" mapstructure:"elastic_ca_cert"`
+	ElasticInsecureSkipVerify bool   `json:"elastic_insecure_skip_verify" mapstructure:"elastic_insecure_skip_verify"`
+	// ElasticBackend is elasticsearch7, elasticsearch8 or opensearch, the
+	// cluster is asked for its version when empty
+	ElasticBackend string `json:"elastic_backend" mapstructure:"elastic_backend"`
+	// ElasticRequestTimeout limits each request from connecting until its
+	// response is read, 0 means no limit
+	ElasticRequestTimeout time.Duration `json:"elastic_request_timeout" mapstructure:"elastic_request_timeout"`
+
+	Categories map[string][]string `json:"categories" mapstructure:"categories"`
+	TagFilters TagFilters          `json:"tag_filters" mapstructure:"tag_filters"`
+	Server     Server              `json:"server" mapstructure:"server"`
+	Metrics    Metrics             `json:"metrics" mapstructure:"metrics"`
+	Log        Log                 `json:"log" mapstructure:"log"`
+	// ElasticHeaders are added to every request to elasticsearch
+	ElasticHeaders map[string]string `json:"elastic_headers" mapstructure:"elastic_headers"`
 }
 
+// Get reads ariadna.yml or ariadna.json from the current or parent
+// directory, see Load
 func Get() (*Ariadna, error) {
+	return Load("")
+}
+
+// Load reads the config file at path, or searches for it when path is
+// empty. A missing file is not an error when searching, so the whole
+// config can come from environment variables. Environment variables
+// override the file, flags bound to viper override both.
+func Load(path string) (*Ariadna, error) {
 	var a Ariadna
-	viper.SetConfigName("ariadna")
-	viper.AddConfigPath(".")
-	viper.AddConfigPath("..")
-	envVariables := []string{"elastic_index", "elastic_urls"}
-	for _, env := range envVariables {
-		if err := viper.BindEnv(env); err != nil {
-			return nil, err
-		}
+	if path != "" {
+		viper.SetConfigFile(path)
+	} else {
+		viper.SetConfigName("ariadna")
+		viper.AddConfigPath(".")
+		viper.AddConfigPath("..")
+	}
+	viper.SetDefault("store", StoreElastic)
+	viper.SetDefault("data_dir", DefaultDataDir)
+	viper.SetDefault("categories", DefaultCategories)
+	viper.SetDefault("crossroad_cluster_distance", DefaultCrossroadClusterDistance)
+	viper.SetDefault("keep_indices", DefaultKeepIndices)
+	viper.SetDefault("progress_interval", DefaultProgressInterval)
+	viper.SetDefault("tag_filters.highway", DefaultTagFilters.Highway)
+	viper.SetDefault("tag_filters.area", DefaultTagFilters.Area)
+	viper.SetDefault("tag_filters.district", DefaultTagFilters.District)
+	viper.SetDefault("tag_filters.quarter", DefaultTagFilters.Quarter)
+	viper.SetDefault("tag_filters.address", DefaultTagFilters.Address)
+	viper.SetDefault("server.listen", DefaultServer.Listen)
+	viper.SetDefault("server.static_dir", DefaultServer.StaticDir)
+	viper.SetDefault("server.read_timeout", DefaultServer.ReadTimeout)
+	viper.SetDefault("server.write_timeout", DefaultServer.WriteTimeout)
+	viper.SetDefault("server.idle_timeout", DefaultServer.IdleTimeout)
+	viper.SetDefault("log.level", DefaultLog.Level)
+	viper.SetDefault("log.format", DefaultLog.Format)
+	viper.SetDefault("log.output", DefaultLog.Output)
+	if err := bindEnv(); err != nil {
+		return nil, err
 	}
 	err := viper.ReadInConfig()
-	if err != nil {
+	if _, notFound := err.(viper.ConfigFileNotFoundError); err != nil && !(notFound && path == "") {
 		return nil, err
 	}
 	err = viper.Unmarshal(&a)
 	if err != nil {
 		return nil, err
 	}
+	if err := a.Validate(); err != nil {
+		return nil, err
+	}
 	return &a, nil
 }
@@ -3,7 +3,9 @@ package config
 import (
 	"os"
 	"testing"
+	"time"
 
+	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -19,3 +21,67 @@ func TestConfig(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, "override", c.ElasticIndex)
 }
+
+func TestTagFilters(t *testing.T) {
+	c, err := Get()
+	require.NoError(t, err)
+	assert.Equal(t, DefaultTagFilters, c.TagFilters)
+
+	f := DefaultTagFilters
+	f.Highway = nil
+	assert.EqualError(t, f.Validate(), "tag_filters.highway: must not be empty")
+	f = DefaultTagFilters
+	f.Address = map[string]string{"": "name"}
+	assert.EqualError(t, f.Validate(), "tag_filters.address: blank key")
+}
+
+func TestEnv(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("ARIADNA_ELASTIC_URLS", "http://es1:9200,http://es2:9200")
+	os.Setenv("ARIADNA_IMPORT_COUNTRY", "Казахстан")
+	os.Setenv("ARIADNA_SERVER_READ_TIMEOUT", "5s")
+	os.Setenv("ARIADNA_TWO_PASS", "true")
+	os.Setenv("ARIADNA_ELASTIC_INDEX", "prefixed")
+	os.Setenv("ELASTIC_INDEX", "legacy")
+	defer os.Clearenv()
+	c, err := Get()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"http://es1:9200", "http://es2:9200"}, c.ElasticURLs)
+	assert.Equal(t, "Казахстан", c.ImportCountry)
+	assert.Equal(t, 5*time.Second, c.Server.ReadTimeout)
+	assert.True(t, c.TwoPass)
+	assert.Equal(t, "prefixed", c.ElasticIndex)
+
+	// an explicit file must exist, the search paths are dropped with it
+	defer viper.Reset()
+	_, err = Load("missing.yml")
+	assert.Error(t, err)
+}
+
+func TestValidate(t *testing.T) {
+	c, err := Get()
+	require.NoError(t, err)
+	c.ElasticIndex = "Addresses"
+	c.ElasticURLs = []string{"http://localhost:9200", "localhost:9200"}
+	c.ImportCountry = ""
+	c.KeepIndices = -1
+	c.Server.TLSKey = "key.pem"
+	c.Log.Format = "xml"
+	assert.EqualError(t, c.Validate(), `invalid config:
+  elastic_index: "Addresses" must be lowercase
+  elastic_urls[1]: "localhost:9200" must be an http or https URL
+  keep_indices: must not be negative, got -1
+  server.tls_cert and server.tls_key must be set together
+  log.format: "xml" must be text or json`)
+
+	c, err = Get()
+	require.NoError(t, err)
+	c.OSMURL = ""
+	c.ImportCountry = ""
+	assert.NoError(t, c.Validate(), "commands not reading the extract do not need it")
+	assert.EqualError(t, c.ValidateImport(true), `invalid config:
+  osm_url: must not be empty
+  import_country: must not be empty, set it to the name tag of the country relation`)
+	assert.EqualError(t, c.ValidateImport(false), `invalid config:
+  import_country: must not be empty, set it to the name tag of the country relation`)
+}
@@ -0,0 +1,96 @@
+package config
+
+import "time"
+
+// Stores documents are imported to
+const (
+	StoreElastic  = "elastic"
+	StoreEmbedded = "embedded"
+)
+
+// DefaultDataDir keeps the embedded index next to the working directory
+const DefaultDataDir = "data"
+
+// DefaultCrossroadClusterDistance covers the nodes of divided roads junctions
+const DefaultCrossroadClusterDistance = 50.0
+
+// DefaultKeepIndices keeps one previous index to roll back to
+const DefaultKeepIndices = 1
+
+// DefaultProgressInterval logs the import progress every ten seconds
+const DefaultProgressInterval = 10 * time.Second
+
+// DefaultServer serves the API on port 8080 with the bundled web page
+var DefaultServer = Server{
+	Listen:       ":8080",
+	StaticDir:    "public",
+	ReadTimeout:  10 * time.Second,
+	WriteTimeout: 30 * time.Second,
+	IdleTimeout:  2 * time.Minute,
+}
+
+// DefaultLog logs human readable lines of info level and above to stderr
+var DefaultLog = Log{
+	Level:  "info",
+	Format: LogFormatText,
+	Output: LogOutputStderr,
+}
+
+// DefaultTagFilters are the OSM tags used to pick elements for import
+var DefaultTagFilters = TagFilters{
+	Highway: []string{
+		"motorway", "trunk", "primary", "secondary", "tertiary",
+		"unclassified", "residential", "living_street", "service", "road",
+	},
+	Area:     []string{"town", "city", "village", "hamlet"},
+	District: []string{"neighbourhood", "suburb"},
+	Quarter:  []string{"quarter", "microdistrict"},
+	Address: map[string]string{
+		"addr:street":      "addr:housenumber",
+		"amenity":          "name",
+		"building":         "name",
+		"addr:housenumber": "",
+		"shop":             "name",
+		"office":           "name",
+		"public_transport": "name",
+		"cuisine":          "name",
+		"railway":          "name",
+		"sport":            "name",
+		"natural":          "name",
+		"tourism":          "name",
+		"leisure":          "name",
+		"historic":         "name",
+		"man_made":         "name",
+		"landuse":          "name",
+		"waterway":         "name",
+		"aerialway":        "name",
+		"aeroway":          "name",
+		"craft":            "name",
+		"military":         "name",
+		"healthcare":       "name",
+	},
+}
+
+// DefaultCategories maps POI categories to the OSM tags they are built from.
+// A selector is either "key=value" or "key=*" to match any value of the key.
+var DefaultCategories = map[string][]string{
+	"pharmacy":   {"amenity=pharmacy", "healthcare=pharmacy"},
+	"hospital":   {"amenity=hospital", "amenity=clinic", "healthcare=hospital", "healthcare=clinic"},
+	"doctor":     {"amenity=doctors", "amenity=dentist", "healthcare=doctor", "healthcare=dentist"},
+	"food":       {"amenity=restaurant", "amenity=cafe", "amenity=fast_food", "amenity=food_court"},
+	"bar":        {"amenity=bar", "amenity=pub"},
+	"bank":       {"amenity=bank", "amenity=atm", "amenity=bureau_de_change"},
+	"fuel":       {"amenity=fuel"},
+	"education":  {"amenity=school", "amenity=kindergarten", "amenity=college", "amenity=university"},
+	"grocery":    {"shop=supermarket", "shop=convenience", "shop=greengrocer", "amenity=marketplace"},
+	"shop":       {"shop=*"},
+	"hotel":      {"tourism=hotel", "tourism=hostel", "tourism=guest_house", "tourism=motel"},
+	"transport":  {"highway=bus_stop", "amenity=bus_station", "public_transport=station", "railway=station", "aeroway=aerodrome"},
+	"parking":    {"amenity=parking"},
+	"worship":    {"amenity=place_of_worship"},
+	"government": {"office=government", "amenity=townhall", "amenity=courthouse"},
+	"police":     {"amenity=police"},
+	"post":       {"amenity=post_office"},
+	"sight":      {"tourism=attraction", "tourism=museum", "tourism=viewpoint", "historic=*"},
+	"leisure":    {"leisure=park", "leisure=sports_centre", "leisure=stadium", "amenity=cinema", "amenity=theatre"},
+}
@@ -0,0 +1,62 @@
+package config
+
+import (
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// EnvPrefix prefixes environment variables overriding config keys, for
+// example server.listen is read from ARIADNA_SERVER_LISTEN. Lists are
+// given as comma-separated values.
+const EnvPrefix = "ARIADNA"
+
+// legacyEnv are unprefixed variables read when the prefixed ones are unset
+var legacyEnv = map[string]string{
+	"elastic_index": "ELASTIC_INDEX",
+	"elastic_urls":  "ELASTIC_URLS",
+}
+
+// bindEnv binds every config key except maps to its environment variable
+func bindEnv() error {
+	for _, key := range keys(reflect.TypeOf(Ariadna{}), "") {
+		name := envName(key)
+		if legacy, ok := legacyEnv[key]; ok {
+			if _, set := os.LookupEnv(name); !set {
+				name = legacy
+			}
+		}
+		if err := viper.BindEnv(key, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// envName returns the environment variable of the config key
+func envName(key string) string {
+	return EnvPrefix + "_" + strings.ToUpper(strings.Replace(key, ".", "_", -1))
+}
+
+// keys lists config keys of the struct fields, nested structs are joined
+// with dots the way viper addresses them
+func keys(t reflect.Type, prefix string) []string {
+	var list []string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		key := prefix + f.Tag.Get("mapstructure")
+		switch f.Type.Kind() {
+		case reflect.Map:
+			continue
+		case reflect.Struct:
+			if f.Type.PkgPath() == t.PkgPath() {
+				list = append(list, keys(f.Type, key+".")...)
+				continue
+			}
+		}
+		list = append(list, key)
+	}
+	return list
+}
@@ -0,0 +1,53 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+)
+
+// TagFilters describes which OSM elements are imported
+type TagFilters struct {
+	// Highway values of ways used to find crossroads
+	Highway []string `json:"highway" mapstructure:"highway"`
+	// Area place values of relations treated as cities, towns and villages
+	Area []string `json:"area" mapstructure:"area"`
+	// District place values of ways treated as city districts
+	District []string `json:"district" mapstructure:"district"`
+	// Quarter place values of ways and relations treated as microdistricts
+	Quarter []string `json:"quarter" mapstructure:"quarter"`
+	// Address maps a tag key to another tag which must be present as well.
+	// An empty value means the key alone is enough.
+	Address map[string]string `json:"address" mapstructure:"address"`
+}
+
+// Validate checks that every rule set is present and has no blank entries
+func (f *TagFilters) Validate() error {
+	lists := []struct {
+		name   string
+		values []string
+	}{
+		{"highway", f.Highway},
+		{"area", f.Area},
+		{"district", f.District},
+		{"quarter", f.Quarter},
+	}
+	for _, l := range lists {
+		if len(l.values) == 0 {
+			return fmt.Errorf("tag_filters.%s: must not be empty", l.name)
+		}
+		for _, v := range l.values {
+			if v == "" {
+				return fmt.Errorf("tag_filters.%s: blank value", l.name)
+			}
+		}
+	}
+	if len(f.Address) == 0 {
+		return errors.New("tag_filters.address: must not be empty")
+	}
+	for k := range f.Address {
+		if k == "" {
+			return errors.New("tag_filters.address: blank key")
+		}
+	}
+	return nil
+}
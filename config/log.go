@@ -0,0 +1,45 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Log formats
+const (
+	LogFormatText = "text"
+	LogFormatJSON = "json"
+)
+
+// Log outputs besides a file path
+const (
+	LogOutputStdout = "stdout"
+	LogOutputStderr = "stderr"
+)
+
+// Log holds settings of the logger shared by every component
+type Log struct {
+	// Level is panic, fatal, error, warn, info, debug or trace
+	Level string `json:"level" mapstructure:"level"`
+	// Format is text or json
+	Format string `json:"format" mapstructure:"format"`
+	// Output is stdout, stderr or a path of a file to append to
+	Output string `json:"output" mapstructure:"output"`
+}
+
+// Validate checks the level and the format
+func (l *Log) Validate() error {
+	if _, err := logrus.ParseLevel(l.Level); err != nil {
+		return fmt.Errorf("log.level: %v", err)
+	}
+	switch l.Format {
+	case LogFormatText, LogFormatJSON:
+	default:
+		return fmt.Errorf("log.format: %q must be %s or %s", l.Format, LogFormatText, LogFormatJSON)
+	}
+	if l.Output == "" {
+		return fmt.Errorf("log.output: must be %s, %s or a file path", LogOutputStdout, LogOutputStderr)
+	}
+	return nil
+}
@@ -0,0 +1,20 @@
+package config
+
+// Metrics holds settings of prometheus metrics. The API serves them at
+// /metrics of the server, commands running to completion use these.
+type Metrics struct {
+	// Listen is the address import and update serve /metrics on while
+	// running, empty disables it
+	Listen string `json:"listen" mapstructure:"listen"`
+	// PushGateway is the URL of a prometheus pushgateway import and update
+	// push metrics to before exiting, empty disables it
+	PushGateway string `json:"pushgateway" mapstructure:"pushgateway"`
+}
+
+// Validate checks the pushgateway URL
+func (m *Metrics) Validate() error {
+	if m.PushGateway == "" {
+		return nil
+	}
+	return validateURL("metrics.pushgateway", m.PushGateway)
+}
@@ -0,0 +1,50 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Server holds settings of the HTTP API
+type Server struct {
+	// Listen is the address to listen on, like ":8080"
+	Listen string `json:"listen" mapstructure:"listen"`
+	// StaticDir is served on paths not matching the API, empty disables it
+	StaticDir    string        `json:"static_dir" mapstructure:"static_dir"`
+	ReadTimeout  time.Duration `json:"read_timeout" mapstructure:"read_timeout"`
+	WriteTimeout time.Duration `json:"write_timeout" mapstructure:"write_timeout"`
+	IdleTimeout  time.Duration `json:"idle_timeout" mapstructure:"idle_timeout"`
+	// TLSCert and TLSKey are paths of PEM files, HTTPS is served when set
+	TLSCert string `json:"tls_cert" mapstructure:"tls_cert"`
+	TLSKey  string `json:"tls_key" mapstructure:"tls_key"`
+}
+
+// TLS reports whether HTTPS is configured
+func (s *Server) TLS() bool {
+	return s.TLSCert != ""
+}
+
+// Validate checks the listen address, timeouts and TLS files
+func (s *Server) Validate() error {
+	if s.Listen == "" {
+		return errors.New("server.listen: must not be empty")
+	}
+	timeouts := []struct {
+		name  string
+		value time.Duration
+	}{
+		{"read_timeout", s.ReadTimeout},
+		{"write_timeout", s.WriteTimeout},
+		{"idle_timeout", s.IdleTimeout},
+	}
+	for _, t := range timeouts {
+		if t.value < 0 {
+			return fmt.Errorf("server.%s: must not be negative", t.name)
+		}
+	}
+	if (s.TLSCert == "") != (s.TLSKey == "") {
+		return errors.New("server.tls_cert and server.tls_key must be set together")
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// ValidationError lists every invalid config key
+type ValidationError []error
+
+func (e ValidationError) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+	return "invalid config:\n  " + strings.Join(msgs, "\n  ")
+}
+
+// Validate checks keys every command needs and returns a ValidationError
+// naming the invalid ones. Keys of the extract are checked by
+// ValidateImport.
+func (a *Ariadna) Validate() error {
+	var errs ValidationError
+	add := func(err error) {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	add(validateIndexName(a.ElasticIndex))
+	switch a.Store {
+	case StoreElastic:
+		a.validateElastic(add)
+	case StoreEmbedded:
+		if a.DataDir == "" {
+			add(errors.New("data_dir: must not be empty for the embedded store"))
+		}
+	default:
+		add(fmt.Errorf("store: %q must be %s or %s", a.Store, StoreElastic, StoreEmbedded))
+	}
+	if a.OSMURL != "" {
+		add(validateURL("osm_url", a.OSMURL))
+	}
+	if a.CrossroadClusterDistance < 0 {
+		add(fmt.Errorf("crossroad_cluster_distance: must not be negative, got %v", a.CrossroadClusterDistance))
+	}
+	if a.KeepIndices < 0 {
+		add(fmt.Errorf("keep_indices: must not be negative, got %d", a.KeepIndices))
+	}
+	if a.ProgressInterval < 0 {
+		add(fmt.Errorf("progress_interval: must not be negative, got %s", a.ProgressInterval))
+	}
+	add(a.TagFilters.Validate())
+	add(a.Server.Validate())
+	add(a.Metrics.Validate())
+	add(a.Log.Validate())
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// ValidateImport checks keys of the extract read by import and update,
+// osm_url is required if the extract is downloaded
+func (a *Ariadna) ValidateImport(download bool) error {
+	var errs ValidationError
+	if download && a.OSMURL == "" {
+		errs = append(errs, errors.New("osm_url: must not be empty"))
+	}
+	if a.OSMFilename == "" {
+		errs = append(errs, errors.New("osm_filename: must not be empty"))
+	}
+	if a.ImportCountry == "" {
+		errs = append(errs, errors.New("import_country: must not be empty, set it to the name tag of the country relation"))
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// validateElastic checks the cluster connection settings
+func (a *Ariadna) validateElastic(add func(error)) {
+	if len(a.ElasticURLs) == 0 && a.ElasticCloudID == "" {
+		add(errors.New("elastic_urls: must not be empty unless elastic_cloud_id is set"))
+	}
+	if len(a.ElasticURLs) > 0 && a.ElasticCloudID != "" {
+		add(errors.New("elastic_urls and elastic_cloud_id must not be set together"))
+	}
+	if (a.ElasticUsername == "") != (a.ElasticPassword == "") {
+		add(errors.New("elastic_username and elastic_password must be set together"))
+	}
+	if a.ElasticAPIKey != "" && a.ElasticUsername != "" {
+		add(errors.New("elastic_api_key and elastic_username must not be set together"))
+	}
+	switch a.ElasticBackend {
+	case "", "elasticsearch7", "elasticsearch8", "opensearch":
+	default:
+		add(fmt.Errorf("elastic_backend: %q must be elasticsearch7, elasticsearch8, opensearch or empty to detect it", a.ElasticBackend))
+	}
+	if a.ElasticRequestTimeout < 0 {
+		add(fmt.Errorf("elastic_request_timeout: must not be negative, got %s", a.ElasticRequestTimeout))
+	}
+	for k := range a.ElasticHeaders {
+		if k == "" {
+			add(errors.New("elastic_headers: blank key"))
+		}
+	}
+	for n, u := range a.ElasticURLs {
+		add(validateURL(fmt.Sprintf("elastic_urls[%d]", n), u))
+	}
+}
+
+// validateIndexName checks the elasticsearch restrictions on index names
+func validateIndexName(name string) error {
+	switch {
+	case name == "":
+		return errors.New("elastic_index: must not be empty")
+	case name != strings.ToLower(name):
+		return fmt.Errorf("elastic_index: %q must be lowercase", name)
+	case strings.ContainsAny(name, ` "*\<|,>/?#:`):
+		return fmt.Errorf(`elastic_index: %q must not contain spaces or any of "*\<|,>/?#:`, name)
+	case strings.HasPrefix(name, "-") || strings.HasPrefix(name, "_") || strings.HasPrefix(name, "+"):
+		return fmt.Errorf("elastic_index: %q must not start with -, _ or +", name)
+	}
+	return nil
+}
+
+// validateURL checks that raw is an absolute http or https URL
+func validateURL(key, raw string) error {
+	if raw == "" {
+		return fmt.Errorf("%s: must not be empty", key)
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("%s: %v", key, err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%s: %q must be an http or https URL", key, raw)
+	}
+	return nil
+}
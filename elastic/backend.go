@@ -0,0 +1,138 @@
+package elastic
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+
+	es7 "github.com/elastic/go-elasticsearch/v7"
+)
+
+// Backends which can be set with elastic_backend, by default the backend
+// is picked by the version the cluster reports
+const (
+	BackendElasticsearch7 = "elasticsearch7"
+	BackendElasticsearch8 = "elasticsearch8"
+	BackendOpenSearch     = "opensearch"
+)
+
+// Backend performs the requests Client is built on with the client library
+// matching the cluster. Responses are returned as is, an error means the
+// request could not be performed at all.
+type Backend interface {
+	// Name returns one of the Backend* constants
+	Name() string
+	CreateIndex(ctx context.Context, index string, body io.Reader) (*Response, error)
+	DeleteIndices(ctx context.Context, indices []string) (*Response, error)
+	// GetIndices returns index settings keyed by names matching pattern
+	GetIndices(ctx context.Context, pattern string) (*Response, error)
+	// GetAlias returns indices the alias points to
+	GetAlias(ctx context.Context, alias string) (*Response, error)
+	UpdateAliases(ctx context.Context, body io.Reader) (*Response, error)
+	PutMapping(ctx context.Context, index string, body io.Reader) (*Response, error)
+	Bulk(ctx context.Context, index string, body io.Reader) (*Response, error)
+	Search(ctx context.Context, index string, body io.Reader) (*Response, error)
+}
+
+// Response is a response of any backend
+type Response struct {
+	StatusCode int
+	Body       io.ReadCloser
+}
+
+// IsError reports whether the cluster rejected the request
+func (r *Response) IsError() bool {
+	return r.StatusCode > 299
+}
+
+// String returns the status and the body, it consumes the body
+func (r *Response) String() string {
+	body, _ := ioutil.ReadAll(r.Body)
+	return fmt.Sprintf("[%d %s] %s", r.StatusCode, http.StatusText(r.StatusCode), body)
+}
+
+// connConfig holds connection settings shared by all client libraries
+type connConfig struct {
+	addresses []string
+	username  string
+	password  string
+	transport http.RoundTripper
+}
+
+// newBackend creates the backend by name, its requests are observed by
+// metrics
+func newBackend(name string, conn connConfig) (Backend, error) {
+	var (
+		b   Backend
+		err error
+	)
+	switch name {
+	case BackendElasticsearch7:
+		b, err = newV7(conn)
+	case BackendElasticsearch8:
+		b, err = newV8(conn)
+	case BackendOpenSearch:
+		b, err = newOpenSearch(conn)
+	default:
+		return nil, fmt.Errorf("unknown backend %q", name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return instrumented{b}, nil
+}
+
+// detectBackend asks the cluster for its version. The v7 client is used
+// since it talks to any cluster without checking the product.
+func detectBackend(ctx context.Context, conn connConfig) (string, error) {
+	c, err := es7.NewClient(es7.Config{
+		Addresses: conn.addresses,
+		Username:  conn.username,
+		Password:  conn.password,
+		Transport: conn.transport,
+	})
+	if err != nil {
+		return "", err
+	}
+	res, err := c.Info(c.Info.WithContext(ctx))
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return "", fmt.Errorf("could not get cluster version: %v", res)
+	}
+	var info struct {
+		Version struct {
+			Number       string `json:"number"`
+			Distribution string `json:"distribution"`
+		} `json:"version"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&info); err != nil {
+		return "", err
+	}
+	return backendFor(info.Version.Distribution, info.Version.Number)
+}
+
+// backendFor returns the backend for the distribution and version number
+func backendFor(distribution, number string) (string, error) {
+	if distribution == "opensearch" {
+		return BackendOpenSearch, nil
+	}
+	major, err := strconv.Atoi(strings.SplitN(number, ".", 2)[0])
+	if err != nil {
+		return "", fmt.Errorf("could not parse cluster version %q", number)
+	}
+	switch {
+	case major == 7:
+		return BackendElasticsearch7, nil
+	case major >= 8:
+		return BackendElasticsearch8, nil
+	}
+	return "", fmt.Errorf("elasticsearch %s is not supported, 7 or newer is required", number)
+}
@@ -0,0 +1,68 @@
+package elastic
+
+import (
+	"context"
+	"io"
+
+	"github.com/opensearch-project/opensearch-go/v2"
+	"github.com/opensearch-project/opensearch-go/v2/opensearchapi"
+)
+
+// openSearch talks to opensearch 1 and 2
+type openSearch struct {
+	c *opensearch.Client
+}
+
+func newOpenSearch(conn connConfig) (Backend, error) {
+	c, err := opensearch.NewClient(opensearch.Config{
+		Addresses: conn.addresses,
+		Username:  conn.username,
+		Password:  conn.password,
+		Transport: conn.transport,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return openSearch{c: c}, nil
+}
+
+func (b openSearch) Name() string { return BackendOpenSearch }
+
+func (b openSearch) CreateIndex(ctx context.Context, index string, body io.Reader) (*Response, error) {
+	return openSearchResponse(b.c.Indices.Create(index, b.c.Indices.Create.WithBody(body), b.c.Indices.Create.WithContext(ctx)))
+}
+
+func (b openSearch) DeleteIndices(ctx context.Context, indices []string) (*Response, error) {
+	return openSearchResponse(b.c.Indices.Delete(indices, b.c.Indices.Delete.WithContext(ctx)))
+}
+
+func (b openSearch) GetIndices(ctx context.Context, pattern string) (*Response, error) {
+	return openSearchResponse(b.c.Indices.Get([]string{pattern}, b.c.Indices.Get.WithContext(ctx)))
+}
+
+func (b openSearch) GetAlias(ctx context.Context, alias string) (*Response, error) {
+	return openSearchResponse(b.c.Indices.GetAlias(b.c.Indices.GetAlias.WithName(alias), b.c.Indices.GetAlias.WithContext(ctx)))
+}
+
+func (b openSearch) UpdateAliases(ctx context.Context, body io.Reader) (*Response, error) {
+	return openSearchResponse(b.c.Indices.UpdateAliases(body, b.c.Indices.UpdateAliases.WithContext(ctx)))
+}
+
+func (b openSearch) PutMapping(ctx context.Context, index string, body io.Reader) (*Response, error) {
+	return openSearchResponse(b.c.Indices.PutMapping(body, b.c.Indices.PutMapping.WithIndex(index), b.c.Indices.PutMapping.WithContext(ctx)))
+}
+
+func (b openSearch) Bulk(ctx context.Context, index string, body io.Reader) (*Response, error) {
+	return openSearchResponse(b.c.Bulk(body, b.c.Bulk.WithIndex(index), b.c.Bulk.WithContext(ctx)))
+}
+
+func (b openSearch) Search(ctx context.Context, index string, body io.Reader) (*Response, error) {
+	return openSearchResponse(b.c.Search(b.c.Search.WithIndex(index), b.c.Search.WithBody(body), b.c.Search.WithContext(ctx)))
+}
+
+func openSearchResponse(res *opensearchapi.Response, err error) (*Response, error) {
+	if err != nil {
+		return nil, err
+	}
+	return &Response{StatusCode: res.StatusCode, Body: res.Body}, nil
+}
@@ -0,0 +1,55 @@
+package elastic
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/maddevsio/ariadna/config"
+	"github.com/maddevsio/ariadna/model"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackendFor(t *testing.T) {
+	for _, tc := range []struct {
+		distribution, number, backend string
+	}{
+		{"", "7.2.0", BackendElasticsearch7},
+		{"", "8.11.1", BackendElasticsearch8},
+		{"opensearch", "2.11.0", BackendOpenSearch},
+		// opensearch pretending to be elasticsearch for old clients
+		{"opensearch", "7.10.2", BackendOpenSearch},
+	} {
+		backend, err := backendFor(tc.distribution, tc.number)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.backend, backend, tc.number)
+	}
+	_, err := backendFor("", "6.8.0")
+	assert.EqualError(t, err, "elasticsearch 6.8.0 is not supported, 7 or newer is required")
+}
+
+func TestBackendsSearch(t *testing.T) {
+	var path string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		w.Write([]byte(`{"hits": {"hits": [{"_source": {"name": "Неман"}}]}}`))
+	}))
+	defer srv.Close()
+	for _, backend := range []string{BackendElasticsearch7, BackendElasticsearch8, BackendOpenSearch} {
+		c, err := New(&config.Ariadna{
+			ElasticIndex:   "addresses",
+			ElasticURLs:    []string{srv.URL},
+			ElasticBackend: backend,
+		}, logrus.New())
+		require.NoError(t, err)
+		addresses, err := c.Reverse(context.Background(), 42.87, 74.59)
+		require.NoError(t, err, backend)
+		assert.Equal(t, []model.Address{{Name: "Неман"}}, addresses, backend)
+		assert.Equal(t, "/addresses/_search", path, backend)
+	}
+}
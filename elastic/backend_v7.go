@@ -0,0 +1,68 @@
+package elastic
+
+import (
+	"context"
+	"io"
+
+	es "github.com/elastic/go-elasticsearch/v7"
+	"github.com/elastic/go-elasticsearch/v7/esapi"
+)
+
+// v7 talks to elasticsearch 7
+type v7 struct {
+	c *es.Client
+}
+
+func newV7(conn connConfig) (Backend, error) {
+	c, err := es.NewClient(es.Config{
+		Addresses: conn.addresses,
+		Username:  conn.username,
+		Password:  conn.password,
+		Transport: conn.transport,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v7{c: c}, nil
+}
+
+func (b v7) Name() string { return BackendElasticsearch7 }
+
+func (b v7) CreateIndex(ctx context.Context, index string, body io.Reader) (*Response, error) {
+	return v7Response(b.c.Indices.Create(index, b.c.Indices.Create.WithBody(body), b.c.Indices.Create.WithContext(ctx)))
+}
+
+func (b v7) DeleteIndices(ctx context.Context, indices []string) (*Response, error) {
+	return v7Response(b.c.Indices.Delete(indices, b.c.Indices.Delete.WithContext(ctx)))
+}
+
+func (b v7) GetIndices(ctx context.Context, pattern string) (*Response, error) {
+	return v7Response(b.c.Indices.Get([]string{pattern}, b.c.Indices.Get.WithContext(ctx)))
+}
+
+func (b v7) GetAlias(ctx context.Context, alias string) (*Response, error) {
+	return v7Response(b.c.Indices.GetAlias(b.c.Indices.GetAlias.WithName(alias), b.c.Indices.GetAlias.WithContext(ctx)))
+}
+
+func (b v7) UpdateAliases(ctx context.Context, body io.Reader) (*Response, error) {
+	return v7Response(b.c.Indices.UpdateAliases(body, b.c.Indices.UpdateAliases.WithContext(ctx)))
+}
+
+func (b v7) PutMapping(ctx context.Context, index string, body io.Reader) (*Response, error) {
+	return v7Response(b.c.Indices.PutMapping(body, b.c.Indices.PutMapping.WithIndex(index), b.c.Indices.PutMapping.WithContext(ctx)))
+}
+
+func (b v7) Bulk(ctx context.Context, index string, body io.Reader) (*Response, error) {
+	return v7Response(b.c.Bulk(body, b.c.Bulk.WithIndex(index), b.c.Bulk.WithContext(ctx)))
+}
+
+func (b v7) Search(ctx context.Context, index string, body io.Reader) (*Response, error) {
+	return v7Response(b.c.Search(b.c.Search.WithIndex(index), b.c.Search.WithBody(body), b.c.Search.WithContext(ctx)))
+}
+
+func v7Response(res *esapi.Response, err error) (*Response, error) {
+	if err != nil {
+		return nil, err
+	}
+	return &Response{StatusCode: res.StatusCode, Body: res.Body}, nil
+}
@@ -0,0 +1,68 @@
+package elastic
+
+import (
+	"context"
+	"io"
+
+	es "github.com/elastic/go-elasticsearch/v8"
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+)
+
+// v8 talks to elasticsearch 8
+type v8 struct {
+	c *es.Client
+}
+
+func newV8(conn connConfig) (Backend, error) {
+	c, err := es.NewClient(es.Config{
+		Addresses: conn.addresses,
+		Username:  conn.username,
+		Password:  conn.password,
+		Transport: conn.transport,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v8{c: c}, nil
+}
+
+func (b v8) Name() string { return BackendElasticsearch8 }
+
+func (b v8) CreateIndex(ctx context.Context, index string, body io.Reader) (*Response, error) {
+	return v8Response(b.c.Indices.Create(index, b.c.Indices.Create.WithBody(body), b.c.Indices.Create.WithContext(ctx)))
+}
+
+func (b v8) DeleteIndices(ctx context.Context, indices []string) (*Response, error) {
+	return v8Response(b.c.Indices.Delete(indices, b.c.Indices.Delete.WithContext(ctx)))
+}
+
+func (b v8) GetIndices(ctx context.Context, pattern string) (*Response, error) {
+	return v8Response(b.c.Indices.Get([]string{pattern}, b.c.Indices.Get.WithContext(ctx)))
+}
+
+func (b v8) GetAlias(ctx context.Context, alias string) (*Response, error) {
+	return v8Response(b.c.Indices.GetAlias(b.c.Indices.GetAlias.WithName(alias), b.c.Indices.GetAlias.WithContext(ctx)))
+}
+
+func (b v8) UpdateAliases(ctx context.Context, body io.Reader) (*Response, error) {
+	return v8Response(b.c.Indices.UpdateAliases(body, b.c.Indices.UpdateAliases.WithContext(ctx)))
+}
+
+func (b v8) PutMapping(ctx context.Context, index string, body io.Reader) (*Response, error) {
+	return v8Response(b.c.Indices.PutMapping([]string{index}, body, b.c.Indices.PutMapping.WithContext(ctx)))
+}
+
+func (b v8) Bulk(ctx context.Context, index string, body io.Reader) (*Response, error) {
+	return v8Response(b.c.Bulk(body, b.c.Bulk.WithIndex(index), b.c.Bulk.WithContext(ctx)))
+}
+
+func (b v8) Search(ctx context.Context, index string, body io.Reader) (*Response, error) {
+	return v8Response(b.c.Search(b.c.Search.WithIndex(index), b.c.Search.WithBody(body), b.c.Search.WithContext(ctx)))
+}
+
+func v8Response(res *esapi.Response, err error) (*Response, error) {
+	if err != nil {
+		return nil, err
+	}
+	return &Response{StatusCode: res.StatusCode, Body: res.Body}, nil
+}
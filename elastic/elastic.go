@@ -5,105 +5,230 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"strings"
+	"sync"
 	"time"
 
-	es "github.com/elastic/go-elasticsearch/v7"
-	"github.com/elastic/go-elasticsearch/v7/esapi"
 	"github.com/maddevsio/ariadna/config"
+	"github.com/maddevsio/ariadna/logging"
+	"github.com/maddevsio/ariadna/store"
 	"github.com/sirupsen/logrus"
 )
 
+// Client is a store.Store backed by an elasticsearch or opensearch cluster
 type Client struct {
-	conn         *es.Client
+	conn         connConfig
+	mu           sync.Mutex
+	b            Backend
 	config       *config.Ariadna
 	createdIndex string
-	logger       *logrus.Logger
+	logger       logrus.FieldLogger
 }
 
-func New(conf *config.Ariadna) (*Client, error) {
-	c, err := es.NewClient(es.Config{
-		Addresses: conf.ElasticURLs,
-	})
+var _ store.Store = (*Client)(nil)
+
+// New creates a client with addresses, credentials and TLS settings from
+// conf. Unless elastic_backend is set the cluster is asked for its version
+// on the first request. Lines are logged to the logger carried by the
+// request context or to logger.
+func New(conf *config.Ariadna, logger logrus.FieldLogger) (*Client, error) {
+	conn, err := connection(conf)
 	if err != nil {
 		return nil, err
 	}
-	return &Client{conn: c, config: conf, logger: logrus.New()}, nil
+	c := &Client{conn: conn, config: conf, logger: logger}
+	if conf.ElasticBackend != "" {
+		if c.b, err = newBackend(conf.ElasticBackend, conn); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+func (c *Client) log(ctx context.Context) logrus.FieldLogger {
+	return logging.FromContext(ctx, c.logger)
 }
-func (c *Client) UpdateIndex() error {
+
+// backend returns the backend, detecting it if it is not known yet. A
+// failed detection is retried on the next call.
+func (c *Client) backend(ctx context.Context) (Backend, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.b != nil {
+		return c.b, nil
+	}
+	name, err := detectBackend(ctx, c.conn)
+	if err != nil {
+		return nil, fmt.Errorf("could not detect backend: %v", err)
+	}
+	b, err := newBackend(name, c.conn)
+	if err != nil {
+		return nil, err
+	}
+	c.log(ctx).Infof("using %s backend", name)
+	c.b = b
+	return b, nil
+}
+
+// CreateIndex creates a new index generation to import documents into.
+// The alias is moved to it by SwapAlias once the import succeeds. Meta is
+// kept in the _meta field of the mapping.
+func (c *Client) CreateIndex(ctx context.Context, meta store.Meta) error {
 	c.createdIndex = fmt.Sprintf("%s-%d", c.config.ElasticIndex, time.Now().Unix())
-	r := &esapi.IndicesCreateRequest{Index: c.createdIndex}
+	b, err := c.backend(ctx)
+	if err != nil {
+		return err
+	}
+	metaJSON, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
 	data := `
 {
     "mappings": {
+			"_meta": ` + string(metaJSON) + `,
 			"properties": {
-				"location": {"type":"geo_point"}
+				"location": {"type":"geo_point"},
+				"postcode": {"type":"keyword"},
+				"osm_type": {"type":"keyword"},
+				"osm_id": {"type":"long"},
+				"layer": {"type":"keyword"},
+				"categories": {"type":"keyword"},
+				"intersection": {"type":"boolean"},
+				"streets": {"type":"text"},
+				"node_ids": {"type":"long"},
+				"tags": {"type":"object", "enabled": false}
 			}
     }
 }`
-	r.Body = bytes.NewReader([]byte(data))
-	res, err := r.Do(context.TODO(), c.conn.Transport)
+	res, err := b.CreateIndex(ctx, c.createdIndex, strings.NewReader(data))
 	if err != nil {
 		return err
 	}
+	defer res.Body.Close()
 	if res.IsError() {
-		return fmt.Errorf("could not update settings: %v", res)
+		return fmt.Errorf("could not create index: %v", res)
+	}
+	c.log(ctx).Infof("created index %s", c.createdIndex)
+	return nil
+}
+
+// Close does nothing, connections are closed by the http transport
+func (c *Client) Close() error {
+	return nil
+}
+
+// CreatedIndex returns the name of the index created by CreateIndex
+func (c *Client) CreatedIndex() string {
+	return c.createdIndex
+}
+
+// DeleteCreatedIndex removes the index created by CreateIndex, it is used
+// to drop a partially filled index when the import fails
+func (c *Client) DeleteCreatedIndex(ctx context.Context) error {
+	if c.createdIndex == "" {
+		return nil
 	}
-	c.logger.Infof("created index %s", c.createdIndex)
-	res, err = c.conn.Indices.PutAlias([]string{c.createdIndex}, c.config.ElasticIndex)
+	b, err := c.backend(ctx)
 	if err != nil {
 		return err
 	}
+	res, err := b.DeleteIndices(ctx, []string{c.createdIndex})
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
 	if res.IsError() {
-		return fmt.Errorf("could not create alias: %v", res)
+		return fmt.Errorf("could not delete index %s: %v", c.createdIndex, res)
 	}
-	c.logger.Info("alias was created")
+	c.log(ctx).Infof("deleted index %s", c.createdIndex)
 	return nil
 }
-func (c *Client) DeleteIndices() error {
-	var indicesToDelete []string
-	r := esapi.IndicesGetAliasRequest{Name: []string{c.config.ElasticIndex}}
-	res, err := r.Do(context.TODO(), c.conn.Transport)
+
+// BulkWrite writes documents to the created index
+func (c *Client) BulkWrite(ctx context.Context, docs []store.Document) error {
+	return c.bulk(ctx, c.createdIndex, docs)
+}
+
+// BulkUpdate writes and deletes documents of the index behind the alias
+func (c *Client) BulkUpdate(ctx context.Context, docs []store.Document) error {
+	return c.bulk(ctx, c.config.ElasticIndex, docs)
+}
+
+func (c *Client) bulk(ctx context.Context, index string, docs []store.Document) error {
+	body, err := bulkBody(docs)
 	if err != nil {
 		return err
 	}
-	if res.IsError() {
-		return fmt.Errorf("could not create alias: %v", res)
-	}
-	data, err := ioutil.ReadAll(res.Body)
+	b, err := c.backend(ctx)
 	if err != nil {
 		return err
 	}
-	var schema map[string]interface{}
-	err = json.Unmarshal(data, &schema)
+	res, err := b.Bulk(ctx, index, body)
 	if err != nil {
 		return err
 	}
-	for key := range schema {
-		if key != c.createdIndex && strings.Contains(key, c.config.ElasticIndex) {
-			indicesToDelete = append(indicesToDelete, key)
-		}
+	defer res.Body.Close()
+	if res.IsError() {
+		return fmt.Errorf("could not perform bulk insert: %v", res)
 	}
-	res, err = c.conn.Indices.Delete(indicesToDelete)
-	if err != nil {
+	var br bulkResponse
+	if err := json.NewDecoder(res.Body).Decode(&br); err != nil {
 		return err
 	}
-	if res.IsError() {
-		return fmt.Errorf("could not delete indices: %v", res)
+	if br.Errors {
+		return br.err()
 	}
-	c.logger.Infof("deleted indices: %v", indicesToDelete)
+	c.log(ctx).Debug("bulk insert is finished")
 	return nil
 }
 
-func (c *Client) BulkWrite(buf bytes.Buffer) error {
-	res, err := c.conn.Bulk(bytes.NewReader(buf.Bytes()), c.conn.Bulk.WithIndex(c.createdIndex))
-	if err != nil {
-		return err
+// bulkBody encodes documents as bulk actions: an index action line
+// followed by the document or a delete action line
+func bulkBody(docs []store.Document) (*bytes.Buffer, error) {
+	var buf bytes.Buffer
+	for _, doc := range docs {
+		if doc.Address == nil {
+			fmt.Fprintf(&buf, `{ "delete": { "_id": "%s" } }%s`, doc.ID, "\n")
+			continue
+		}
+		data, err := json.Marshal(doc.Address)
+		if err != nil {
+			return nil, fmt.Errorf("document %s: %v", doc.ID, err)
+		}
+		fmt.Fprintf(&buf, `{ "index": { "_id": "%s" } }%s`, doc.ID, "\n")
+		buf.Write(data)
+		buf.WriteString("\n")
 	}
-	if res.IsError() {
-		return fmt.Errorf("could not perform bulk insert: %v", res)
+	return &buf, nil
+}
+
+// bulkResponse is a response to a bulk request, it succeeds even if some
+// of the documents were rejected
+type bulkResponse struct {
+	Errors bool `json:"errors"`
+	Items  []map[string]struct {
+		ID     string          `json:"_id"`
+		Status int             `json:"status"`
+		Error  json.RawMessage `json:"error"`
+	} `json:"items"`
+}
+
+func (br *bulkResponse) err() error {
+	var (
+		failed int
+		first  string
+	)
+	for _, item := range br.Items {
+		for _, result := range item {
+			if result.Error == nil {
+				continue
+			}
+			if failed == 0 {
+				first = fmt.Sprintf("%s: %s", result.ID, result.Error)
+			}
+			failed++
+		}
 	}
-	c.logger.Info("bulk insert is finished")
-	return nil
+	return fmt.Errorf("bulk insert failed for %d of %d documents, first error: %s", failed, len(br.Items), first)
 }
@@ -0,0 +1,28 @@
+package elastic
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/maddevsio/ariadna/model"
+	"github.com/maddevsio/ariadna/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBulkBody(t *testing.T) {
+	body, err := bulkBody([]store.Document{
+		{ID: "n1", Address: &model.Address{Name: "Неман"}},
+		{ID: "w2"},
+	})
+	require.NoError(t, err)
+	lines := strings.Split(body.String(), "\n")
+	require.Len(t, lines, 4)
+	assert.Equal(t, `{ "index": { "_id": "n1" } }`, lines[0])
+	var a model.Address
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &a))
+	assert.Equal(t, model.Address{Name: "Неман"}, a)
+	assert.Equal(t, `{ "delete": { "_id": "w2" } }`, lines[2])
+	assert.Empty(t, lines[3])
+}
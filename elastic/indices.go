@@ -0,0 +1,173 @@
+package elastic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+
+	"github.com/maddevsio/ariadna/store"
+)
+
+// Indices returns index generations, oldest first
+func (c *Client) Indices(ctx context.Context) ([]store.Index, error) {
+	names, err := c.generations(ctx)
+	if err != nil {
+		return nil, err
+	}
+	aliased, err := c.aliasIndices(ctx)
+	if err != nil {
+		return nil, err
+	}
+	indices := make([]store.Index, 0, len(names))
+	for _, name := range names {
+		indices = append(indices, store.Index{Name: name, Aliased: contains(aliased, name)})
+	}
+	return indices, nil
+}
+
+// SwapAlias atomically points the alias to the created index only
+func (c *Client) SwapAlias(ctx context.Context) error {
+	return c.pointAlias(ctx, c.createdIndex)
+}
+
+// Rollback points the alias to the generation preceding the current one
+// and returns its name
+func (c *Client) Rollback(ctx context.Context) (string, error) {
+	indices, err := c.Indices(ctx)
+	if err != nil {
+		return "", err
+	}
+	previous, err := store.Previous(indices)
+	if err != nil {
+		return "", err
+	}
+	return previous, c.pointAlias(ctx, previous)
+}
+
+// Prune deletes generations which the alias does not point to, except for
+// the created index and keep most recent ones. It returns deleted indices.
+func (c *Client) Prune(ctx context.Context, keep int) ([]string, error) {
+	indices, err := c.Indices(ctx)
+	if err != nil {
+		return nil, err
+	}
+	indicesToDelete := store.Prunable(indices, c.createdIndex, keep)
+	if len(indicesToDelete) == 0 {
+		return nil, nil
+	}
+	b, err := c.backend(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res, err := b.DeleteIndices(ctx, indicesToDelete)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return nil, fmt.Errorf("could not delete indices: %v", res)
+	}
+	c.log(ctx).Infof("deleted indices: %v", indicesToDelete)
+	return indicesToDelete, nil
+}
+
+// pointAlias atomically moves the alias to the index
+func (c *Client) pointAlias(ctx context.Context, index string) error {
+	current, err := c.aliasIndices(ctx)
+	if err != nil {
+		return err
+	}
+	actions := []map[string]interface{}{
+		{"add": map[string]string{"index": index, "alias": c.config.ElasticIndex}},
+	}
+	for _, name := range current {
+		if name != index {
+			actions = append(actions, map[string]interface{}{
+				"remove": map[string]string{"index": name, "alias": c.config.ElasticIndex},
+			})
+		}
+	}
+	body, err := json.Marshal(map[string]interface{}{"actions": actions})
+	if err != nil {
+		return err
+	}
+	b, err := c.backend(ctx)
+	if err != nil {
+		return err
+	}
+	res, err := b.UpdateAliases(ctx, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return fmt.Errorf("could not update alias: %v", res)
+	}
+	c.log(ctx).Infof("alias %s points to %s", c.config.ElasticIndex, index)
+	return nil
+}
+
+// aliasIndices returns indices the alias points to
+func (c *Client) aliasIndices(ctx context.Context) ([]string, error) {
+	b, err := c.backend(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res, err := b.GetAlias(ctx, c.config.ElasticIndex)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if res.IsError() {
+		return nil, fmt.Errorf("could not get alias: %v", res)
+	}
+	return decodeIndexNames(res.Body)
+}
+
+// generations returns all indices created for the alias, oldest first
+func (c *Client) generations(ctx context.Context) ([]string, error) {
+	b, err := c.backend(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res, err := b.GetIndices(ctx, c.config.ElasticIndex+"-*")
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return nil, fmt.Errorf("could not list indices: %v", res)
+	}
+	return decodeIndexNames(res.Body)
+}
+
+// decodeIndexNames returns sorted keys of a response keyed by index name.
+// Generations are suffixed by a unix timestamp, so they sort by age.
+func decodeIndexNames(body io.Reader) ([]string, error) {
+	var schema map[string]json.RawMessage
+	if err := json.NewDecoder(body).Decode(&schema); err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(schema))
+	for name := range schema {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
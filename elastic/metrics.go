@@ -0,0 +1,73 @@
+package elastic
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/maddevsio/ariadna/metrics"
+)
+
+// instrumented observes latency and failures of backend requests
+type instrumented struct {
+	Backend
+}
+
+// observe records the request of the operation started at started. Not
+// found responses are answers about missing indices, not failures.
+func observe(operation string, started time.Time, res *Response, err error) (*Response, error) {
+	metrics.ElasticDuration.WithLabelValues(operation).Observe(time.Since(started).Seconds())
+	if err != nil || (res.IsError() && res.StatusCode != http.StatusNotFound) {
+		metrics.ElasticErrors.WithLabelValues(operation).Inc()
+	}
+	return res, err
+}
+
+func (b instrumented) CreateIndex(ctx context.Context, index string, body io.Reader) (*Response, error) {
+	started := time.Now()
+	res, err := b.Backend.CreateIndex(ctx, index, body)
+	return observe("create_index", started, res, err)
+}
+
+func (b instrumented) DeleteIndices(ctx context.Context, indices []string) (*Response, error) {
+	started := time.Now()
+	res, err := b.Backend.DeleteIndices(ctx, indices)
+	return observe("delete_indices", started, res, err)
+}
+
+func (b instrumented) GetIndices(ctx context.Context, pattern string) (*Response, error) {
+	started := time.Now()
+	res, err := b.Backend.GetIndices(ctx, pattern)
+	return observe("get_indices", started, res, err)
+}
+
+func (b instrumented) GetAlias(ctx context.Context, alias string) (*Response, error) {
+	started := time.Now()
+	res, err := b.Backend.GetAlias(ctx, alias)
+	return observe("get_alias", started, res, err)
+}
+
+func (b instrumented) UpdateAliases(ctx context.Context, body io.Reader) (*Response, error) {
+	started := time.Now()
+	res, err := b.Backend.UpdateAliases(ctx, body)
+	return observe("update_aliases", started, res, err)
+}
+
+func (b instrumented) PutMapping(ctx context.Context, index string, body io.Reader) (*Response, error) {
+	started := time.Now()
+	res, err := b.Backend.PutMapping(ctx, index, body)
+	return observe("put_mapping", started, res, err)
+}
+
+func (b instrumented) Bulk(ctx context.Context, index string, body io.Reader) (*Response, error) {
+	started := time.Now()
+	res, err := b.Backend.Bulk(ctx, index, body)
+	return observe("bulk", started, res, err)
+}
+
+func (b instrumented) Search(ctx context.Context, index string, body io.Reader) (*Response, error) {
+	started := time.Now()
+	res, err := b.Backend.Search(ctx, index, body)
+	return observe("search", started, res, err)
+}
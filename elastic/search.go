@@ -0,0 +1,169 @@
+package elastic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/maddevsio/ariadna/model"
+	"github.com/maddevsio/ariadna/store"
+)
+
+type searchResponse struct {
+	Hits struct {
+		Hits []struct {
+			Source model.Address `json:"_source"`
+		} `json:"hits"`
+	} `json:"hits"`
+}
+
+// Search returns addresses matching the free form query. Queries naming
+// two streets, like "Чуй и Советская", are looked up among crossroads first.
+func (c *Client) Search(ctx context.Context, query string) ([]model.Address, error) {
+	if first, second, ok := store.ParseIntersection(query); ok {
+		addresses, err := c.search(ctx, intersectionQuery(first, second))
+		if err != nil || len(addresses) > 0 {
+			return addresses, err
+		}
+	}
+	return c.search(ctx, searchQuery(query))
+}
+
+// Reverse returns addresses nearest to the given point
+func (c *Client) Reverse(ctx context.Context, lat, lon float64) ([]model.Address, error) {
+	return c.search(ctx, reverseQuery(lat, lon))
+}
+
+// Places returns places of the category within the radius, nearest first
+func (c *Client) Places(ctx context.Context, q store.PlacesQuery) ([]model.Address, error) {
+	return c.search(ctx, placesQuery(q))
+}
+
+func (c *Client) search(ctx context.Context, body map[string]interface{}) ([]model.Address, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	b, err := c.backend(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res, err := b.Search(ctx, c.config.ElasticIndex, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return nil, fmt.Errorf("could not perform search: %v", res)
+	}
+	var sr searchResponse
+	if err := json.NewDecoder(res.Body).Decode(&sr); err != nil {
+		return nil, err
+	}
+	addresses := make([]model.Address, 0, len(sr.Hits.Hits))
+	for _, hit := range sr.Hits.Hits {
+		addresses = append(addresses, hit.Source)
+	}
+	return addresses, nil
+}
+
+// searchQuery builds a full text query. Tokens that look like a postcode
+// are matched exactly against the postcode field, microdistrict designations
+// like "мкр" are dropped since quarter names are indexed without them.
+func searchQuery(query string) map[string]interface{} {
+	terms, postcodes := store.SplitQuery(query)
+	boolQuery := map[string]interface{}{}
+	if len(postcodes) > 0 {
+		boolQuery["filter"] = map[string]interface{}{
+			"terms": map[string]interface{}{"postcode": postcodes},
+		}
+	}
+	if len(terms) > 0 {
+		boolQuery["must"] = map[string]interface{}{
+			"multi_match": map[string]interface{}{
+				"query":    strings.Join(terms, " "),
+				"type":     "cross_fields",
+				"operator": "and",
+				"fields": []string{
+					"name^3", "street^2", "quarter^2", "housenumber",
+					"city", "town", "village", "district",
+				},
+			},
+		}
+	}
+	return map[string]interface{}{
+		"size":  store.SearchSize,
+		"query": map[string]interface{}{"bool": boolQuery},
+	}
+}
+
+func intersectionQuery(first, second string) map[string]interface{} {
+	streetMatch := func(street string) map[string]interface{} {
+		return map[string]interface{}{
+			"match": map[string]interface{}{
+				"streets": map[string]interface{}{"query": street, "operator": "and"},
+			},
+		}
+	}
+	return map[string]interface{}{
+		"size": store.SearchSize,
+		"query": map[string]interface{}{
+			"bool": map[string]interface{}{
+				"filter": map[string]interface{}{
+					"term": map[string]interface{}{"intersection": true},
+				},
+				"must": []interface{}{streetMatch(first), streetMatch(second)},
+			},
+		},
+	}
+}
+
+func reverseQuery(lat, lon float64) map[string]interface{} {
+	return map[string]interface{}{
+		"size":  store.SearchSize,
+		"query": map[string]interface{}{"match_all": map[string]interface{}{}},
+		"sort": []interface{}{
+			map[string]interface{}{
+				"_geo_distance": map[string]interface{}{
+					"location": map[string]float64{"lat": lat, "lon": lon},
+					"order":    "asc",
+					"unit":     "m",
+				},
+			},
+		},
+	}
+}
+
+func placesQuery(q store.PlacesQuery) map[string]interface{} {
+	location := map[string]float64{"lat": q.Lat, "lon": q.Lon}
+	return map[string]interface{}{
+		"from": q.From,
+		"size": q.Size,
+		"query": map[string]interface{}{
+			"bool": map[string]interface{}{
+				"filter": []interface{}{
+					map[string]interface{}{
+						"term": map[string]interface{}{"categories": q.Category},
+					},
+					map[string]interface{}{
+						"geo_distance": map[string]interface{}{
+							"distance": fmt.Sprintf("%fm", q.Radius),
+							"location": location,
+						},
+					},
+				},
+			},
+		},
+		"sort": []interface{}{
+			map[string]interface{}{
+				"_geo_distance": map[string]interface{}{
+					"location": location,
+					"order":    "asc",
+					"unit":     "m",
+				},
+			},
+		},
+	}
+}
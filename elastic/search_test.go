@@ -0,0 +1,28 @@
+package elastic
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearchQueryPostcode(t *testing.T) {
+	q := searchQuery("720040 Киевская 5")
+	boolQuery := q["query"].(map[string]interface{})["bool"].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{
+		"terms": map[string]interface{}{"postcode": []string{"720040"}},
+	}, boolQuery["filter"])
+	match := boolQuery["must"].(map[string]interface{})["multi_match"].(map[string]interface{})
+	assert.Equal(t, "Киевская 5", match["query"])
+
+	q = searchQuery("720040")
+	boolQuery = q["query"].(map[string]interface{})["bool"].(map[string]interface{})
+	assert.NotContains(t, boolQuery, "must")
+}
+
+func TestSearchQueryQuarter(t *testing.T) {
+	q := searchQuery("мкр Джал, 23")
+	boolQuery := q["query"].(map[string]interface{})["bool"].(map[string]interface{})
+	match := boolQuery["must"].(map[string]interface{})["multi_match"].(map[string]interface{})
+	assert.Equal(t, "Джал, 23", match["query"])
+}
@@ -0,0 +1,110 @@
+package elastic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/maddevsio/ariadna/store"
+)
+
+// Status returns the index behind the alias with its document count and
+// the meta stored by CreateIndex
+func (c *Client) Status(ctx context.Context) (store.Status, error) {
+	var st store.Status
+	names, err := c.aliasIndices(ctx)
+	if err != nil || len(names) == 0 {
+		return st, err
+	}
+	st.Index = names[0]
+	if st.Meta, err = c.meta(ctx, st.Index); err != nil {
+		return st, err
+	}
+	st.Documents, err = c.count(ctx, st.Index)
+	return st, err
+}
+
+// meta reads the _meta field of the index mapping, indices created before
+// it was introduced have none
+func (c *Client) meta(ctx context.Context, index string) (store.Meta, error) {
+	var meta store.Meta
+	b, err := c.backend(ctx)
+	if err != nil {
+		return meta, err
+	}
+	res, err := b.GetIndices(ctx, index)
+	if err != nil {
+		return meta, err
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return meta, fmt.Errorf("could not get index %s: %v", index, res)
+	}
+	var indices map[string]struct {
+		Mappings struct {
+			Meta store.Meta `json:"_meta"`
+		} `json:"mappings"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&indices); err != nil {
+		return meta, err
+	}
+	return indices[index].Mappings.Meta, nil
+}
+
+// count returns the number of documents in the index
+func (c *Client) count(ctx context.Context, index string) (uint64, error) {
+	b, err := c.backend(ctx)
+	if err != nil {
+		return 0, err
+	}
+	res, err := b.Search(ctx, index, strings.NewReader(`{"size": 0, "track_total_hits": true}`))
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return 0, fmt.Errorf("could not count documents: %v", res)
+	}
+	var sr struct {
+		Hits struct {
+			Total struct {
+				Value uint64 `json:"value"`
+			} `json:"total"`
+		} `json:"hits"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&sr); err != nil {
+		return 0, err
+	}
+	return sr.Hits.Total.Value, nil
+}
+
+// SetMeta replaces the _meta field of the mapping of the index behind the
+// alias
+func (c *Client) SetMeta(ctx context.Context, meta store.Meta) error {
+	names, err := c.aliasIndices(ctx)
+	if err != nil {
+		return err
+	}
+	if len(names) == 0 {
+		return fmt.Errorf("no index is served, run import first")
+	}
+	body, err := json.Marshal(map[string]interface{}{"_meta": meta})
+	if err != nil {
+		return err
+	}
+	b, err := c.backend(ctx)
+	if err != nil {
+		return err
+	}
+	res, err := b.PutMapping(ctx, names[0], bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return fmt.Errorf("could not update meta of %s: %v", names[0], res)
+	}
+	return nil
+}
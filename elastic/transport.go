@@ -0,0 +1,154 @@
+package elastic
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/maddevsio/ariadna/config"
+)
+
+// headerTransport adds headers to every request
+type headerTransport struct {
+	header http.Header
+	next   http.RoundTripper
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// a RoundTripper must not modify the request, so headers are copied
+	r := *req
+	r.Header = make(http.Header, len(req.Header)+len(t.header))
+	for k, v := range req.Header {
+		r.Header[k] = v
+	}
+	for k, v := range t.header {
+		r.Header[k] = v
+	}
+	return t.next.RoundTrip(&r)
+}
+
+// timeoutTransport limits a request from connecting until its response
+// body is closed, so stalled uploads and downloads fail too. Retries get a
+// limit of their own.
+type timeoutTransport struct {
+	timeout time.Duration
+	next    http.RoundTripper
+}
+
+func (t *timeoutTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, cancel := context.WithTimeout(req.Context(), t.timeout)
+	res, err := t.next.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	res.Body = &cancelBody{ReadCloser: res.Body, cancel: cancel}
+	return res, nil
+}
+
+// cancelBody releases the request context when the body is closed
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// connection builds the connection settings shared by the importer and
+// the search API
+func connection(conf *config.Ariadna) (connConfig, error) {
+	cfg := connConfig{
+		addresses: conf.ElasticURLs,
+		username:  conf.ElasticUsername,
+		password:  conf.ElasticPassword,
+	}
+	if conf.ElasticCloudID != "" {
+		addr, err := cloudAddress(conf.ElasticCloudID)
+		if err != nil {
+			return cfg, fmt.Errorf("elastic_cloud_id: %v", err)
+		}
+		cfg.addresses = []string{addr}
+	}
+	transport := &http.Transport{
+		Proxy:               http.ProxyFromEnvironment,
+		MaxIdleConnsPerHost: 10,
+		IdleConnTimeout:     90 * time.Second,
+		TLSHandshakeTimeout: 10 * time.Second,
+	}
+	if conf.ElasticCACert != "" || conf.ElasticInsecureSkipVerify {
+		tlsConfig := &tls.Config{InsecureSkipVerify: conf.ElasticInsecureSkipVerify}
+		if conf.ElasticCACert != "" {
+			pool, err := certPool(conf.ElasticCACert)
+			if err != nil {
+				return cfg, fmt.Errorf("elastic_ca_cert: %v", err)
+			}
+			tlsConfig.RootCAs = pool
+		}
+		transport.TLSClientConfig = tlsConfig
+	}
+	header := make(http.Header)
+	for k, v := range conf.ElasticHeaders {
+		header.Set(k, v)
+	}
+	if conf.ElasticAPIKey != "" {
+		header.Set("Authorization", "ApiKey "+conf.ElasticAPIKey)
+	}
+	cfg.transport = transport
+	if conf.ElasticRequestTimeout > 0 {
+		cfg.transport = &timeoutTransport{timeout: conf.ElasticRequestTimeout, next: cfg.transport}
+	}
+	if len(header) > 0 {
+		cfg.transport = &headerTransport{header: header, next: cfg.transport}
+	}
+	return cfg, nil
+}
+
+// certPool returns system certificates with ones from the PEM file added
+func certPool(path string) (*x509.CertPool, error) {
+	pem, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in %s", path)
+	}
+	return pool, nil
+}
+
+// cloudAddress decodes the elasticsearch URL from an Elastic Cloud id,
+// which looks like "name:base64(host$es_uuid$kibana_uuid)"
+func cloudAddress(id string) (string, error) {
+	parts := strings.SplitN(id, ":", 2)
+	if len(parts) != 2 {
+		return "", errors.New("must look like name:base64")
+	}
+	data, err := base64.StdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", err
+	}
+	fields := strings.Split(string(data), "$")
+	if len(fields) < 2 || fields[0] == "" || fields[1] == "" {
+		return "", errors.New("no elasticsearch host in the id")
+	}
+	host, port := fields[0], ""
+	if i := strings.LastIndex(host, ":"); i >= 0 {
+		host, port = host[:i], host[i:]
+	}
+	return "https://" + fields[1] + "." + host + port, nil
+}
@@ -0,0 +1,73 @@
+package elastic
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/maddevsio/ariadna/config"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCloudAddress(t *testing.T) {
+	// base64 of "us-east-1.aws.found.io:9243$abc$def"
+	addr, err := cloudAddress("prod:dXMtZWFzdC0xLmF3cy5mb3VuZC5pbzo5MjQzJGFiYyRkZWY=")
+	require.NoError(t, err)
+	assert.Equal(t, "https://abc.us-east-1.aws.found.io:9243", addr)
+	_, err = cloudAddress("prod")
+	assert.Error(t, err)
+}
+
+func TestClientHeaders(t *testing.T) {
+	var got http.Header
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"version": {"number": "2.11.0", "distribution": "opensearch"}}`))
+	}))
+	defer srv.Close()
+	c, err := New(&config.Ariadna{
+		ElasticIndex:              "addresses",
+		ElasticURLs:               []string{srv.URL},
+		ElasticAPIKey:             "a2V5",
+		ElasticInsecureSkipVerify: true,
+		ElasticHeaders:            map[string]string{"x-tenant": "kg"},
+	}, logrus.New())
+	require.NoError(t, err)
+	b, err := c.backend(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, BackendOpenSearch, b.Name())
+	assert.Equal(t, "ApiKey a2V5", got.Get("Authorization"))
+	assert.Equal(t, "kg", got.Get("X-Tenant"))
+}
+
+func TestRequestTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"version": `))
+		w.(http.Flusher).Flush()
+		// the body stalls after the headers were sent
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer srv.Close()
+	conn, err := connection(&config.Ariadna{
+		ElasticURLs:           []string{srv.URL},
+		ElasticRequestTimeout: 100 * time.Millisecond,
+	})
+	require.NoError(t, err)
+	res, err := (&http.Client{Transport: conn.transport}).Get(srv.URL)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	started := time.Now()
+	_, err = ioutil.ReadAll(res.Body)
+	assert.Error(t, err)
+	assert.True(t, time.Since(started) < 2*time.Second)
+}
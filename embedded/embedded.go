@@ -0,0 +1,374 @@
+// Package embedded keeps the index on local disk with bleve, so a single
+// binary can import and serve a small country without a cluster.
+//
+// Generations are directories named like elasticsearch ones in data_dir,
+// the served one is named in the <elastic_index>.current file.
+package embedded
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/blevesearch/bleve"
+	"github.com/blevesearch/bleve/index/scorch"
+	"github.com/maddevsio/ariadna/config"
+	"github.com/maddevsio/ariadna/logging"
+	"github.com/maddevsio/ariadna/model"
+	"github.com/maddevsio/ariadna/store"
+	"github.com/sirupsen/logrus"
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	// batchSize is the number of documents written to bleve at once
+	batchSize = 1000
+	// lockTimeout limits waiting for a generation opened by another process
+	lockTimeout = time.Second
+	// sourceField keeps the document as it was written
+	sourceField = "_source"
+)
+
+// metaKey is the internal key of store.Meta in a generation
+var metaKey = []byte("meta")
+
+// Store is a store.Store keeping generations in config.DataDir
+type Store struct {
+	config       *config.Ariadna
+	logger       logrus.FieldLogger
+	created      bleve.Index
+	createdIndex string
+
+	// mu guards the served generation which is reopened when the alias
+	// file changes
+	mu          sync.RWMutex
+	served      bleve.Index
+	servedIndex string
+}
+
+var _ store.Store = (*Store)(nil)
+
+// New creates a store in conf.DataDir. Lines are logged to the logger
+// carried by the request context or to logger.
+func New(conf *config.Ariadna, logger logrus.FieldLogger) (*Store, error) {
+	if err := os.MkdirAll(conf.DataDir, 0755); err != nil {
+		return nil, err
+	}
+	return &Store{config: conf, logger: logger}, nil
+}
+
+// CreateIndex creates a new generation to import documents into, meta is
+// kept in its internal storage
+func (s *Store) CreateIndex(ctx context.Context, meta store.Meta) error {
+	s.createdIndex = fmt.Sprintf("%s-%d", s.config.ElasticIndex, time.Now().Unix())
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+	idx, err := bleve.NewUsing(s.path(s.createdIndex), newMapping(), scorch.Name, scorch.Name, nil)
+	if err != nil {
+		return err
+	}
+	if err := idx.SetInternal(metaKey, data); err != nil {
+		idx.Close()
+		return err
+	}
+	s.created = idx
+	s.log(ctx).Infof("created index %s", s.createdIndex)
+	return nil
+}
+
+// BulkWrite writes documents to the created generation
+func (s *Store) BulkWrite(ctx context.Context, docs []store.Document) error {
+	return write(ctx, s.created, docs)
+}
+
+// BulkUpdate writes and deletes documents of the served generation. The
+// generation must not be open in another process, so the server has to be
+// stopped.
+func (s *Store) BulkUpdate(ctx context.Context, docs []store.Document) error {
+	return s.updateServed(func(idx bleve.Index) error {
+		return write(ctx, idx, docs)
+	})
+}
+
+// updateServed opens the served generation for writing and calls fn. The
+// generation this store reads from is released first, queries wait for
+// the update.
+func (s *Store) updateServed(fn func(bleve.Index) error) error {
+	name, err := s.alias()
+	if err != nil {
+		return err
+	}
+	if name == "" {
+		return fmt.Errorf("no index is served, run import first")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.served != nil {
+		s.served.Close()
+		s.served, s.servedIndex = nil, ""
+	}
+	if err := checkUnlocked(s.path(name)); err != nil {
+		return err
+	}
+	idx, err := bleve.Open(s.path(name))
+	if err != nil {
+		return err
+	}
+	defer idx.Close()
+	return fn(idx)
+}
+
+// SwapAlias closes the created generation and starts serving it
+func (s *Store) SwapAlias(ctx context.Context) error {
+	if err := s.created.Close(); err != nil {
+		return err
+	}
+	return s.pointAlias(ctx, s.createdIndex)
+}
+
+// CreatedIndex returns the name of the generation created by CreateIndex
+func (s *Store) CreatedIndex() string {
+	return s.createdIndex
+}
+
+// DeleteCreatedIndex removes the generation of a failed import
+func (s *Store) DeleteCreatedIndex(ctx context.Context) error {
+	if s.created == nil {
+		return nil
+	}
+	s.created.Close()
+	if err := os.RemoveAll(s.path(s.createdIndex)); err != nil {
+		return err
+	}
+	s.log(ctx).Infof("deleted index %s", s.createdIndex)
+	return nil
+}
+
+// Indices returns generations, oldest first
+func (s *Store) Indices(ctx context.Context) ([]store.Index, error) {
+	files, err := ioutil.ReadDir(s.config.DataDir)
+	if err != nil {
+		return nil, err
+	}
+	current, err := s.alias()
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, f := range files {
+		if f.IsDir() && strings.HasPrefix(f.Name(), s.config.ElasticIndex+"-") {
+			names = append(names, f.Name())
+		}
+	}
+	sort.Strings(names)
+	indices := make([]store.Index, 0, len(names))
+	for _, name := range names {
+		indices = append(indices, store.Index{Name: name, Aliased: name == current})
+	}
+	return indices, nil
+}
+
+// Rollback serves the generation preceding the current one
+func (s *Store) Rollback(ctx context.Context) (string, error) {
+	indices, err := s.Indices(ctx)
+	if err != nil {
+		return "", err
+	}
+	previous, err := store.Previous(indices)
+	if err != nil {
+		return "", err
+	}
+	return previous, s.pointAlias(ctx, previous)
+}
+
+// Prune deletes generations which are not served except for the created
+// one and keep most recent ones
+func (s *Store) Prune(ctx context.Context, keep int) ([]string, error) {
+	indices, err := s.Indices(ctx)
+	if err != nil {
+		return nil, err
+	}
+	deleted := store.Prunable(indices, s.createdIndex, keep)
+	for _, name := range deleted {
+		if err := os.RemoveAll(s.path(name)); err != nil {
+			return nil, err
+		}
+	}
+	if len(deleted) > 0 {
+		s.log(ctx).Infof("deleted indices: %v", deleted)
+	}
+	return deleted, nil
+}
+
+// Status returns the served generation with its document count and the
+// meta stored by CreateIndex
+func (s *Store) Status(ctx context.Context) (store.Status, error) {
+	var st store.Status
+	name, err := s.alias()
+	if err != nil || name == "" {
+		return st, err
+	}
+	idx, err := s.servedIndexRLocked(ctx)
+	if err != nil {
+		return st, err
+	}
+	defer s.mu.RUnlock()
+	st.Index = s.servedIndex
+	if st.Documents, err = idx.DocCount(); err != nil {
+		return st, err
+	}
+	data, err := idx.GetInternal(metaKey)
+	if err != nil || data == nil {
+		return st, err
+	}
+	err = json.Unmarshal(data, &st.Meta)
+	return st, err
+}
+
+// SetMeta replaces the meta of the served generation. Like BulkUpdate it
+// needs the server stopped.
+func (s *Store) SetMeta(ctx context.Context, meta store.Meta) error {
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+	return s.updateServed(func(idx bleve.Index) error {
+		return idx.SetInternal(metaKey, data)
+	})
+}
+
+// Close closes open generations
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.served != nil {
+		s.served.Close()
+		s.served, s.servedIndex = nil, ""
+	}
+	return nil
+}
+
+func (s *Store) log(ctx context.Context) logrus.FieldLogger {
+	return logging.FromContext(ctx, s.logger)
+}
+
+func (s *Store) path(name string) string {
+	return filepath.Join(s.config.DataDir, name)
+}
+
+func (s *Store) aliasPath() string {
+	return filepath.Join(s.config.DataDir, s.config.ElasticIndex+".current")
+}
+
+// alias returns the served generation, empty if there is none
+func (s *Store) alias() (string, error) {
+	data, err := ioutil.ReadFile(s.aliasPath())
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	return strings.TrimSpace(string(data)), err
+}
+
+// pointAlias atomically replaces the alias file, servers reopen the index
+// on the next search
+func (s *Store) pointAlias(ctx context.Context, name string) error {
+	tmp := s.aliasPath() + ".tmp"
+	if err := ioutil.WriteFile(tmp, []byte(name+"\n"), 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, s.aliasPath()); err != nil {
+		return err
+	}
+	s.log(ctx).Infof("alias %s points to %s", s.config.ElasticIndex, name)
+	return nil
+}
+
+// servedIndexRLocked returns the served generation with s.mu read locked,
+// reopening it if the alias moved. The caller must unlock s.mu.
+func (s *Store) servedIndexRLocked(ctx context.Context) (bleve.Index, error) {
+	name, err := s.alias()
+	if err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	if name == s.servedIndex && s.served != nil {
+		return s.served, nil
+	}
+	s.mu.RUnlock()
+
+	s.mu.Lock()
+	if name != s.servedIndex || s.served == nil {
+		if s.served != nil {
+			s.served.Close()
+			s.served, s.servedIndex = nil, ""
+		}
+		if name == "" {
+			s.mu.Unlock()
+			return nil, fmt.Errorf("no index is served, run import first")
+		}
+		idx, err := bleve.OpenUsing(s.path(name), map[string]interface{}{"read_only": true})
+		if err != nil {
+			s.mu.Unlock()
+			return nil, err
+		}
+		s.served, s.servedIndex = idx, name
+		s.log(ctx).Infof("serving index %s", name)
+	}
+	s.mu.Unlock()
+	s.mu.RLock()
+	return s.served, nil
+}
+
+// document is indexed by bleve: fields of the address and the address as
+// searches return it
+type document struct {
+	model.Address
+	Source string `json:"_source"`
+}
+
+// write indexes and deletes documents in batches
+func write(ctx context.Context, idx bleve.Index, docs []store.Document) error {
+	batch := idx.NewBatch()
+	for _, doc := range docs {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if doc.Address == nil {
+			batch.Delete(doc.ID)
+		} else {
+			source, err := json.Marshal(doc.Address)
+			if err != nil {
+				return fmt.Errorf("document %s: %v", doc.ID, err)
+			}
+			if err := batch.Index(doc.ID, document{Address: *doc.Address, Source: string(source)}); err != nil {
+				return err
+			}
+		}
+		if batch.Size() < batchSize {
+			continue
+		}
+		if err := idx.Batch(batch); err != nil {
+			return err
+		}
+		batch.Reset()
+	}
+	return idx.Batch(batch)
+}
+
+// checkUnlocked fails if another process holds the generation open
+func checkUnlocked(path string) error {
+	db, err := bolt.Open(filepath.Join(path, "root.bolt"), 0600, &bolt.Options{Timeout: lockTimeout})
+	if err != nil {
+		return fmt.Errorf("index %s is in use, stop the server before updating: %v", filepath.Base(path), err)
+	}
+	return db.Close()
+}
@@ -0,0 +1,118 @@
+package embedded
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/maddevsio/ariadna/config"
+	"github.com/maddevsio/ariadna/model"
+	"github.com/maddevsio/ariadna/store"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func bulk(docs map[string]model.Address, deleted ...string) []store.Document {
+	var list []store.Document
+	for id := range docs {
+		doc := docs[id]
+		list = append(list, store.Document{ID: id, Address: &doc})
+	}
+	for _, id := range deleted {
+		list = append(list, store.Document{ID: id})
+	}
+	return list
+}
+
+func names(addresses []model.Address) []string {
+	var list []string
+	for _, a := range addresses {
+		list = append(list, a.Name)
+	}
+	return list
+}
+
+func TestStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ariadna")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	s, err := New(&config.Ariadna{ElasticIndex: "addresses", DataDir: dir}, logrus.New())
+	require.NoError(t, err)
+	defer s.Close()
+	ctx := context.Background()
+
+	_, err = s.Search(ctx, "Чуй")
+	assert.EqualError(t, err, "no index is served, run import first")
+
+	status, err := s.Status(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, status.Index)
+	imported := time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, s.CreateIndex(ctx, store.Meta{Imported: &imported}))
+	require.NoError(t, s.BulkWrite(ctx, bulk(map[string]model.Address{
+		"w1": {Name: "Дом", Street: "проспект Чуй", HouseNumber: "120", Postcode: "720040",
+			Location: model.Location{Lat: 42.876, Lon: 74.604}},
+		"n2": {Name: "Неман", Categories: []string{"pharmacy"},
+			Location: model.Location{Lat: 42.875, Lon: 74.600}},
+		"n3": {Name: "Аптека далеко", Categories: []string{"pharmacy"},
+			Location: model.Location{Lat: 42.9, Lon: 74.7}},
+		"x4": {Name: "Чуй / Советская", Intersection: true, Streets: []string{"проспект Чуй", "улица Советская"},
+			Location: model.Location{Lat: 42.877, Lon: 74.612}},
+	})))
+	require.NoError(t, s.SwapAlias(ctx))
+
+	status, err = s.Status(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(4), status.Documents)
+	assert.NotEmpty(t, status.Index)
+	require.NotNil(t, status.Imported)
+	assert.True(t, imported.Equal(*status.Imported))
+	assert.Nil(t, status.OSMTimestamp)
+
+	found, err := s.Search(ctx, "чуй 120")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Дом"}, names(found))
+	found, err = s.Search(ctx, "720040 Чуй")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Дом"}, names(found))
+	found, err = s.Search(ctx, "угол Чуй и Советская")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Чуй / Советская"}, names(found))
+
+	found, err = s.Reverse(ctx, 42.875, 74.6001)
+	require.NoError(t, err)
+	assert.Equal(t, "Неман", found[0].Name)
+	found, err = s.Places(ctx, store.PlacesQuery{Category: "pharmacy", Lat: 42.875, Lon: 74.601, Radius: 1000, Size: 10})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Неман"}, names(found))
+
+	// the server has to release the generation before it is updated
+	s.Close()
+	require.NoError(t, s.BulkUpdate(ctx, bulk(nil, "n2")))
+	found, err = s.Places(ctx, store.PlacesQuery{Category: "pharmacy", Lat: 42.875, Lon: 74.601, Radius: 1000, Size: 10})
+	require.NoError(t, err)
+	assert.Empty(t, found)
+
+	// meta is replaced while the store reads from the generation
+	status, err = s.Status(ctx)
+	require.NoError(t, err)
+	updated := time.Date(2020, 5, 2, 0, 0, 0, 0, time.UTC)
+	status.Meta.Updated = &updated
+	require.NoError(t, s.SetMeta(ctx, status.Meta))
+	status, err = s.Status(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, status.Meta.Updated)
+	assert.True(t, updated.Equal(*status.Meta.Updated))
+	require.NotNil(t, status.Meta.Imported)
+	assert.True(t, imported.Equal(*status.Meta.Imported))
+
+	indices, err := s.Indices(ctx)
+	require.NoError(t, err)
+	require.Len(t, indices, 1)
+	assert.True(t, indices[0].Aliased)
+	_, err = s.Rollback(ctx)
+	assert.Error(t, err)
+}
@@ -0,0 +1,176 @@
+package embedded
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/blevesearch/bleve"
+	"github.com/blevesearch/bleve/analysis/analyzer/custom"
+	"github.com/blevesearch/bleve/analysis/analyzer/keyword"
+	"github.com/blevesearch/bleve/analysis/token/lowercase"
+	"github.com/blevesearch/bleve/analysis/tokenizer/unicode"
+	"github.com/blevesearch/bleve/mapping"
+	"github.com/blevesearch/bleve/search"
+	"github.com/blevesearch/bleve/search/query"
+	"github.com/maddevsio/ariadna/model"
+	"github.com/maddevsio/ariadna/store"
+)
+
+// textAnalyzer splits text into lowercased words without dropping any,
+// the standard analyzer removes english stop words
+const textAnalyzer = "text"
+
+// textFields are searched by Search with their boosts, like the
+// elasticsearch multi_match query
+var textFields = []struct {
+	name  string
+	boost float64
+}{
+	{"name", 3}, {"street", 2}, {"quarter", 2}, {"housenumber", 1},
+	{"city", 1}, {"town", 1}, {"village", 1}, {"district", 1},
+}
+
+// newMapping maps fields the way the elasticsearch index does, other
+// fields are kept in the source only
+func newMapping() mapping.IndexMapping {
+	m := bleve.NewIndexMapping()
+	m.AddCustomAnalyzer(textAnalyzer, map[string]interface{}{
+		"type":          custom.Name,
+		"tokenizer":     unicode.Name,
+		"token_filters": []string{lowercase.Name},
+	})
+	m.DefaultAnalyzer = textAnalyzer
+	doc := bleve.NewDocumentStaticMapping()
+	text := bleve.NewTextFieldMapping()
+	text.Store = false
+	for _, f := range textFields {
+		doc.AddFieldMappingsAt(f.name, text)
+	}
+	doc.AddFieldMappingsAt("streets", text)
+	kw := bleve.NewTextFieldMapping()
+	kw.Analyzer = keyword.Name
+	kw.Store = false
+	for _, name := range []string{"postcode", "osm_type", "layer", "categories"} {
+		doc.AddFieldMappingsAt(name, kw)
+	}
+	doc.AddFieldMappingsAt("intersection", bleve.NewBooleanFieldMapping())
+	doc.AddFieldMappingsAt("location", bleve.NewGeoPointFieldMapping())
+	source := bleve.NewTextFieldMapping()
+	source.Index = false
+	source.IncludeInAll = false
+	doc.AddFieldMappingsAt(sourceField, source)
+	m.DefaultMapping = doc
+	return m
+}
+
+// Search returns addresses matching the free form query. Queries naming
+// two streets are looked up among crossroads first.
+func (s *Store) Search(ctx context.Context, q string) ([]model.Address, error) {
+	if first, second, ok := store.ParseIntersection(q); ok {
+		addresses, err := s.search(ctx, intersectionRequest(first, second))
+		if err != nil || len(addresses) > 0 {
+			return addresses, err
+		}
+	}
+	return s.search(ctx, searchRequest(q))
+}
+
+// Reverse returns addresses nearest to the point
+func (s *Store) Reverse(ctx context.Context, lat, lon float64) ([]model.Address, error) {
+	req := bleve.NewSearchRequestOptions(bleve.NewMatchAllQuery(), store.SearchSize, 0, false)
+	if err := sortByDistance(req, lat, lon); err != nil {
+		return nil, err
+	}
+	return s.search(ctx, req)
+}
+
+// Places returns places of the category within the radius, nearest first
+func (s *Store) Places(ctx context.Context, q store.PlacesQuery) ([]model.Address, error) {
+	category := bleve.NewTermQuery(q.Category)
+	category.SetField("categories")
+	within := bleve.NewGeoDistanceQuery(q.Lon, q.Lat, fmt.Sprintf("%fm", q.Radius))
+	within.SetField("location")
+	req := bleve.NewSearchRequestOptions(bleve.NewConjunctionQuery(category, within), q.Size, q.From, false)
+	if err := sortByDistance(req, q.Lat, q.Lon); err != nil {
+		return nil, err
+	}
+	return s.search(ctx, req)
+}
+
+func (s *Store) search(ctx context.Context, req *bleve.SearchRequest) ([]model.Address, error) {
+	idx, err := s.servedIndexRLocked(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer s.mu.RUnlock()
+	req.Fields = []string{sourceField}
+	res, err := idx.SearchInContext(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	addresses := make([]model.Address, 0, len(res.Hits))
+	for _, hit := range res.Hits {
+		source, _ := hit.Fields[sourceField].(string)
+		var a model.Address
+		if err := json.Unmarshal([]byte(source), &a); err != nil {
+			return nil, fmt.Errorf("document %s: %v", hit.ID, err)
+		}
+		addresses = append(addresses, a)
+	}
+	return addresses, nil
+}
+
+// searchRequest requires every term to match one of text fields, tokens
+// that look like a postcode are matched exactly
+func searchRequest(q string) *bleve.SearchRequest {
+	terms, postcodes := store.SplitQuery(q)
+	var must []query.Query
+	for _, term := range terms {
+		var fields []query.Query
+		for _, f := range textFields {
+			m := bleve.NewMatchQuery(term)
+			m.SetField(f.name)
+			m.SetBoost(f.boost)
+			fields = append(fields, m)
+		}
+		must = append(must, bleve.NewDisjunctionQuery(fields...))
+	}
+	if len(postcodes) > 0 {
+		var codes []query.Query
+		for _, code := range postcodes {
+			t := bleve.NewTermQuery(code)
+			t.SetField("postcode")
+			codes = append(codes, t)
+		}
+		must = append(must, bleve.NewDisjunctionQuery(codes...))
+	}
+	var root query.Query = bleve.NewMatchAllQuery()
+	if len(must) > 0 {
+		root = bleve.NewConjunctionQuery(must...)
+	}
+	return bleve.NewSearchRequestOptions(root, store.SearchSize, 0, false)
+}
+
+// intersectionRequest finds crossroads of both streets
+func intersectionRequest(first, second string) *bleve.SearchRequest {
+	intersection := bleve.NewBoolFieldQuery(true)
+	intersection.SetField("intersection")
+	must := []query.Query{intersection}
+	for _, street := range []string{first, second} {
+		m := bleve.NewMatchQuery(street)
+		m.SetField("streets")
+		m.SetOperator(query.MatchQueryOperatorAnd)
+		must = append(must, m)
+	}
+	return bleve.NewSearchRequestOptions(bleve.NewConjunctionQuery(must...), store.SearchSize, 0, false)
+}
+
+func sortByDistance(req *bleve.SearchRequest, lat, lon float64) error {
+	byDistance, err := search.NewSortGeoDistance("location", "m", lon, lat, false)
+	if err != nil {
+		return err
+	}
+	req.SortByCustom(search.SortOrder{byDistance})
+	return nil
+}
@@ -0,0 +1,182 @@
+// Package export writes documents built by the importer to a file instead
+// of an index and reads such files back, so an extract can be parsed once
+// and loaded into any store later.
+package export
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/maddevsio/ariadna/model"
+	"github.com/maddevsio/ariadna/store"
+)
+
+// Formats of exported files
+const (
+	// FormatNDJSON is a document per line with its id in the "id" field
+	FormatNDJSON = "ndjson"
+	// FormatGeoJSON is a FeatureCollection of points with documents in
+	// properties
+	FormatGeoJSON = "geojson"
+	// FormatCSV is a document per row, lists and tags are JSON
+	FormatCSV = "csv"
+)
+
+// FormatOf returns format if it is not empty or guesses it by the file
+// extension
+func FormatOf(path, format string) (string, error) {
+	if format == "" {
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".ndjson", ".jsonl":
+			format = FormatNDJSON
+		case ".geojson", ".json":
+			format = FormatGeoJSON
+		case ".csv":
+			format = FormatCSV
+		default:
+			return "", fmt.Errorf("cannot guess format of %s, set it explicitly", path)
+		}
+	}
+	switch format {
+	case FormatNDJSON, FormatGeoJSON, FormatCSV:
+		return format, nil
+	}
+	return "", fmt.Errorf("unknown format %q, must be one of %s, %s, %s", format, FormatNDJSON, FormatGeoJSON, FormatCSV)
+}
+
+// encoder writes documents of a single format
+type encoder interface {
+	begin() error
+	encode(id string, a model.Address) error
+	end() error
+}
+
+// File is a store.Sink writing documents to a file. The file is written
+// next to the target and renamed by SwapAlias, so a failed export does
+// not replace a previous one.
+type File struct {
+	path   string
+	format string
+
+	mu  sync.Mutex
+	f   *os.File
+	w   *bufio.Writer
+	enc encoder
+}
+
+var _ store.Sink = (*File)(nil)
+
+// New returns a sink writing to path in the format
+func New(path, format string) (*File, error) {
+	format, err := FormatOf(path, format)
+	if err != nil {
+		return nil, err
+	}
+	return &File{path: path, format: format}, nil
+}
+
+func (e *File) tmpPath() string {
+	return e.path + ".tmp"
+}
+
+// CreateIndex creates the temporary file, meta is not stored
+func (e *File) CreateIndex(ctx context.Context, meta store.Meta) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	f, err := os.Create(e.tmpPath())
+	if err != nil {
+		return err
+	}
+	e.f = f
+	e.w = bufio.NewWriter(f)
+	e.enc = newEncoder(e.w, e.format)
+	return e.enc.begin()
+}
+
+func newEncoder(w *bufio.Writer, format string) encoder {
+	switch format {
+	case FormatGeoJSON:
+		return &geojsonEncoder{w: w}
+	case FormatCSV:
+		return newCSVEncoder(w)
+	}
+	return &ndjsonEncoder{w: w}
+}
+
+// CreatedIndex returns the path of the file
+func (e *File) CreatedIndex() string {
+	return e.path
+}
+
+// BulkWrite appends documents to the file
+func (e *File) BulkWrite(ctx context.Context, docs []store.Document) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.enc == nil {
+		return errors.New("export file is not created")
+	}
+	for _, doc := range docs {
+		if doc.Address == nil {
+			return fmt.Errorf("cannot delete %s from an export file", doc.ID)
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := e.enc.encode(doc.ID, *doc.Address); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// BulkUpdate is not supported, export files are written by a full import
+func (e *File) BulkUpdate(ctx context.Context, docs []store.Document) error {
+	return errors.New("export files cannot be updated, run a full export")
+}
+
+// SwapAlias finishes the file and moves it to the target path
+func (e *File) SwapAlias(ctx context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.f == nil {
+		return errors.New("export file is not created")
+	}
+	err := e.enc.end()
+	if err == nil {
+		err = e.w.Flush()
+	}
+	if cerr := e.f.Close(); err == nil {
+		err = cerr
+	}
+	e.f, e.w, e.enc = nil, nil, nil
+	if err != nil {
+		return err
+	}
+	return os.Rename(e.tmpPath(), e.path)
+}
+
+// DeleteCreatedIndex removes the temporary file, it is left behind by
+// SwapAlias too if renaming fails
+func (e *File) DeleteCreatedIndex(ctx context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.f != nil {
+		e.f.Close()
+		e.f, e.w, e.enc = nil, nil, nil
+	}
+	if err := os.Remove(e.tmpPath()); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Prune does nothing, an export replaces the previous file
+func (e *File) Prune(ctx context.Context, keep int) ([]string, error) {
+	return nil, nil
+}
@@ -0,0 +1,76 @@
+package export
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/maddevsio/ariadna/model"
+	"github.com/maddevsio/ariadna/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var docs = map[string]model.Address{
+	"n1": {
+		OSMType: model.NodeType, OSMID: 1, Layer: model.LayerPOI,
+		Categories: []string{"food", "cafe"}, Country: "Кыргызстан", City: "Бишкек",
+		Street: "Киевская, 5", HouseNumber: "5", Name: `Кафе "Ак"`,
+		Location: model.Location{Lat: 42.8746, Lon: 74.6122},
+		Tags:     map[string]string{"opening_hours": "24/7"},
+	},
+	"x2": {
+		OSMType: model.NodeType, OSMID: 9007199254740993, Layer: model.LayerIntersection,
+		// OSM names hold several values separated by ";"
+		Intersection: true, Streets: []string{"Чуй; Chui", "Советская"}, NodeIDs: []int64{2, 9007199254740993},
+		Location: model.Location{Lat: 42.87, Lon: 74.6},
+	},
+}
+
+func TestRoundTrip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "export")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	ctx := context.Background()
+	for _, name := range []string{"out.ndjson", "out.geojson", "out.csv"} {
+		path := filepath.Join(dir, name)
+		f, err := New(path, "")
+		require.NoError(t, err)
+		require.NoError(t, f.CreateIndex(ctx, store.Meta{}))
+		var written []store.Document
+		for id := range docs {
+			a := docs[id]
+			written = append(written, store.Document{ID: id, Address: &a})
+		}
+		require.NoError(t, f.BulkWrite(ctx, written))
+		require.NoError(t, f.SwapAlias(ctx))
+		_, err = os.Stat(path + ".tmp")
+		assert.True(t, os.IsNotExist(err), name)
+
+		in, err := os.Open(path)
+		require.NoError(t, err)
+		got := make(map[string]model.Address)
+		err = Read(in, f.format, func(id string, a model.Address) error {
+			got[id] = a
+			return nil
+		})
+		in.Close()
+		require.NoError(t, err, name)
+		assert.Equal(t, docs, got, name)
+	}
+}
+
+func TestFormatOf(t *testing.T) {
+	format, err := FormatOf("kg.jsonl", "")
+	assert.NoError(t, err)
+	assert.Equal(t, FormatNDJSON, format)
+	format, err = FormatOf("kg.txt", FormatCSV)
+	assert.NoError(t, err)
+	assert.Equal(t, FormatCSV, format)
+	_, err = FormatOf("kg.txt", "")
+	assert.Error(t, err)
+	_, err = FormatOf("kg.csv", "xml")
+	assert.Error(t, err)
+}
@@ -0,0 +1,322 @@
+package export
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/maddevsio/ariadna/model"
+	geojson "github.com/paulmach/go.geojson"
+)
+
+// document is an NDJSON line
+type document struct {
+	ID string `json:"id"`
+	model.Address
+}
+
+// csvHeader lists columns of CSV files
+var csvHeader = []string{
+	"id", "osm_type", "osm_id", "layer", "categories", "country", "city",
+	"village", "town", "district", "quarter", "prefix", "street",
+	"housenumber", "postcode", "name", "intersection", "streets", "node_ids",
+	"lat", "lon", "tags",
+}
+
+type ndjsonEncoder struct {
+	w *bufio.Writer
+}
+
+func (e *ndjsonEncoder) begin() error { return nil }
+func (e *ndjsonEncoder) end() error   { return nil }
+
+func (e *ndjsonEncoder) encode(id string, a model.Address) error {
+	data, err := json.Marshal(document{ID: id, Address: a})
+	if err != nil {
+		return err
+	}
+	e.w.Write(data)
+	return e.w.WriteByte('\n')
+}
+
+type geojsonEncoder struct {
+	w     *bufio.Writer
+	count int
+}
+
+func (e *geojsonEncoder) begin() error {
+	_, err := e.w.WriteString(`{"type":"FeatureCollection","features":[` + "\n")
+	return err
+}
+
+func (e *geojsonEncoder) end() error {
+	_, err := e.w.WriteString("\n]}\n")
+	return err
+}
+
+func (e *geojsonEncoder) encode(id string, a model.Address) error {
+	f, err := toFeature(id, a)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(f)
+	if err != nil {
+		return err
+	}
+	if e.count > 0 {
+		e.w.WriteString(",\n")
+	}
+	e.count++
+	_, err = e.w.Write(data)
+	return err
+}
+
+// toFeature returns a point feature with the document fields except the
+// location in properties
+func toFeature(id string, a model.Address) (*geojson.Feature, error) {
+	data, err := json.Marshal(a)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var props map[string]interface{}
+	if err := dec.Decode(&props); err != nil {
+		return nil, err
+	}
+	delete(props, "location")
+	f := geojson.NewPointFeature([]float64{a.Location.Lon, a.Location.Lat})
+	f.ID = id
+	f.Properties = props
+	return f, nil
+}
+
+// feature is a GeoJSON feature with undecoded properties, so ids do not
+// lose precision as floats
+type feature struct {
+	ID         interface{}       `json:"id"`
+	Geometry   *geojson.Geometry `json:"geometry"`
+	Properties json.RawMessage   `json:"properties"`
+}
+
+// fromFeature is the reverse of toFeature
+func fromFeature(f feature) (string, model.Address, error) {
+	var a model.Address
+	id, ok := f.ID.(string)
+	if !ok || id == "" {
+		return "", a, fmt.Errorf("feature without a string id: %v", f.ID)
+	}
+	if f.Geometry == nil || !f.Geometry.IsPoint() || len(f.Geometry.Point) < 2 {
+		return "", a, fmt.Errorf("feature %s is not a point", id)
+	}
+	if err := json.Unmarshal(f.Properties, &a); err != nil {
+		return "", a, fmt.Errorf("feature %s: %v", id, err)
+	}
+	a.Location = model.Location{Lat: f.Geometry.Point[1], Lon: f.Geometry.Point[0]}
+	return id, a, nil
+}
+
+type csvEncoder struct {
+	w *csv.Writer
+}
+
+func newCSVEncoder(w io.Writer) *csvEncoder {
+	return &csvEncoder{w: csv.NewWriter(w)}
+}
+
+func (e *csvEncoder) begin() error {
+	return e.w.Write(csvHeader)
+}
+
+func (e *csvEncoder) end() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+func (e *csvEncoder) encode(id string, a model.Address) error {
+	var err error
+	// lists and tags are JSON, so values may contain any separator
+	cell := func(v interface{}, size int) string {
+		if err != nil || size == 0 {
+			return ""
+		}
+		data, merr := json.Marshal(v)
+		if merr != nil {
+			err = merr
+		}
+		return string(data)
+	}
+	record := []string{
+		id, a.OSMType, strconv.FormatInt(a.OSMID, 10), a.Layer,
+		cell(a.Categories, len(a.Categories)), a.Country, a.City, a.Village,
+		a.Town, a.District, a.Quarter, a.Prefix, a.Street, a.HouseNumber,
+		a.Postcode, a.Name, strconv.FormatBool(a.Intersection),
+		cell(a.Streets, len(a.Streets)), cell(a.NodeIDs, len(a.NodeIDs)),
+		strconv.FormatFloat(a.Location.Lat, 'f', -1, 64),
+		strconv.FormatFloat(a.Location.Lon, 'f', -1, 64),
+		cell(a.Tags, len(a.Tags)),
+	}
+	if err != nil {
+		return err
+	}
+	return e.w.Write(record)
+}
+
+// fromRecord is the reverse of csvEncoder.encode
+func fromRecord(record []string) (string, model.Address, error) {
+	var a model.Address
+	if len(record) != len(csvHeader) {
+		return "", a, fmt.Errorf("expected %d columns, got %d", len(csvHeader), len(record))
+	}
+	col := make(map[string]string, len(csvHeader))
+	for i, name := range csvHeader {
+		col[name] = record[i]
+	}
+	var err error
+	parse := func(name string, fn func(string) error) {
+		if err == nil && col[name] != "" {
+			if perr := fn(col[name]); perr != nil {
+				err = fmt.Errorf("column %s: %v", name, perr)
+			}
+		}
+	}
+	a = model.Address{
+		OSMType:     col["osm_type"],
+		Layer:       col["layer"],
+		Country:     col["country"],
+		City:        col["city"],
+		Village:     col["village"],
+		Town:        col["town"],
+		District:    col["district"],
+		Quarter:     col["quarter"],
+		Prefix:      col["prefix"],
+		Street:      col["street"],
+		HouseNumber: col["housenumber"],
+		Postcode:    col["postcode"],
+		Name:        col["name"],
+	}
+	parse("osm_id", func(s string) (err error) {
+		a.OSMID, err = strconv.ParseInt(s, 10, 64)
+		return err
+	})
+	parse("intersection", func(s string) (err error) {
+		a.Intersection, err = strconv.ParseBool(s)
+		return err
+	})
+	parse("categories", func(s string) error {
+		return json.Unmarshal([]byte(s), &a.Categories)
+	})
+	parse("streets", func(s string) error {
+		return json.Unmarshal([]byte(s), &a.Streets)
+	})
+	parse("node_ids", func(s string) error {
+		return json.Unmarshal([]byte(s), &a.NodeIDs)
+	})
+	parse("lat", func(s string) (err error) {
+		a.Location.Lat, err = strconv.ParseFloat(s, 64)
+		return err
+	})
+	parse("lon", func(s string) (err error) {
+		a.Location.Lon, err = strconv.ParseFloat(s, 64)
+		return err
+	})
+	parse("tags", func(s string) error {
+		return json.Unmarshal([]byte(s), &a.Tags)
+	})
+	if err != nil {
+		return "", a, err
+	}
+	if col["id"] == "" {
+		return "", a, fmt.Errorf("row without an id")
+	}
+	return col["id"], a, nil
+}
+
+// Read calls fn for every document of the file in the format
+func Read(r io.Reader, format string, fn func(id string, a model.Address) error) error {
+	switch format {
+	case FormatNDJSON:
+		return readNDJSON(r, fn)
+	case FormatGeoJSON:
+		return readGeoJSON(r, fn)
+	case FormatCSV:
+		return readCSV(r, fn)
+	}
+	return fmt.Errorf("unknown format %q", format)
+}
+
+func readNDJSON(r io.Reader, fn func(id string, a model.Address) error) error {
+	dec := json.NewDecoder(r)
+	for n := 1; ; n++ {
+		var doc document
+		err := dec.Decode(&doc)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("document %d: %v", n, err)
+		}
+		if doc.ID == "" {
+			return fmt.Errorf("document %d has no id", n)
+		}
+		if err := fn(doc.ID, doc.Address); err != nil {
+			return err
+		}
+	}
+}
+
+func readGeoJSON(r io.Reader, fn func(id string, a model.Address) error) error {
+	var fc struct {
+		Type     string    `json:"type"`
+		Features []feature `json:"features"`
+	}
+	if err := json.NewDecoder(r).Decode(&fc); err != nil {
+		return err
+	}
+	if fc.Type != "FeatureCollection" {
+		return fmt.Errorf("expected a FeatureCollection, got %q", fc.Type)
+	}
+	for _, f := range fc.Features {
+		id, a, err := fromFeature(f)
+		if err != nil {
+			return err
+		}
+		if err := fn(id, a); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readCSV(r io.Reader, fn func(id string, a model.Address) error) error {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = len(csvHeader)
+	header, err := cr.Read()
+	if err != nil {
+		return fmt.Errorf("header: %v", err)
+	}
+	if strings.Join(header, ",") != strings.Join(csvHeader, ",") {
+		return fmt.Errorf("unexpected header, want %s", strings.Join(csvHeader, ","))
+	}
+	for line := 2; ; line++ {
+		record, err := cr.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		id, a, err := fromRecord(record)
+		if err != nil {
+			return fmt.Errorf("line %d: %v", line, err)
+		}
+		if err := fn(id, a); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package export
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/maddevsio/ariadna/model"
+	"github.com/maddevsio/ariadna/store"
+)
+
+// cleanupTimeout limits serving or deleting of a loaded index
+const cleanupTimeout = 30 * time.Second
+
+// BatchSize is the number of documents sent in a single bulk request
+const BatchSize = 5000
+
+// Load writes documents read from r into a new generation of s and
+// serves it. The generation is deleted if loading or serving it fails. It
+// returns the number of loaded documents.
+func Load(ctx context.Context, s store.Sink, r io.Reader, format string) (int, error) {
+	now := time.Now()
+	if err := s.CreateIndex(ctx, store.Meta{Imported: &now}); err != nil {
+		return 0, err
+	}
+	count, err := write(ctx, s, r, format)
+	// the generation is complete, so a late interrupt does not stop serving
+	// it, nor deleting it if that fails
+	finishCtx, cancel := context.WithTimeout(context.Background(), cleanupTimeout)
+	defer cancel()
+	if err == nil {
+		err = s.SwapAlias(finishCtx)
+	}
+	if err != nil {
+		s.DeleteCreatedIndex(finishCtx)
+		return count, err
+	}
+	return count, nil
+}
+
+func write(ctx context.Context, s store.Sink, r io.Reader, format string) (int, error) {
+	var (
+		docs  []store.Document
+		count int
+	)
+	flush := func() error {
+		if len(docs) == 0 {
+			return nil
+		}
+		err := s.BulkWrite(ctx, docs)
+		docs = nil
+		return err
+	}
+	err := Read(r, format, func(id string, a model.Address) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		docs = append(docs, store.Document{ID: id, Address: &a})
+		count++
+		if count%BatchSize == 0 {
+			return flush()
+		}
+		return nil
+	})
+	if err == nil {
+		err = flush()
+	}
+	return count, err
+}
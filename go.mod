@@ -5,25 +5,31 @@ go 1.12
 require (
 	github.com/aybabtme/iocontrol v0.0.0-20150809002002-ad15bcfc95a0 // indirect
 	github.com/benbjohnson/clock v0.0.0-20161215174838-7dc76406b6d3 // indirect
+	github.com/blevesearch/bleve v1.0.14
 	github.com/davecgh/go-spew v1.1.1
 	github.com/dustin/go-humanize v1.0.0 // indirect
 	github.com/elastic/go-elasticsearch/v7 v7.1.1
+	github.com/elastic/go-elasticsearch/v8 v8.4.0
 	github.com/erikstmartin/go-testdb v0.0.0-20160219214506-8d10e4a1bae5 // indirect
-	github.com/facebookgo/ensure v0.0.0-20160127193407-b4ab57deab51 // indirect
 	github.com/facebookgo/stack v0.0.0-20160209184415-751773369052 // indirect
-	github.com/facebookgo/subset v0.0.0-20150612182917-8dac2c3c4870 // indirect
 	github.com/fortytw2/leaktest v1.3.0 // indirect
+	github.com/golang/protobuf v1.3.2
 	github.com/julienschmidt/httprouter v1.2.0
 	github.com/kellydunn/golang-geo v0.7.0
 	github.com/kylelemons/go-gypsy v0.0.0-20160905020020-08cad365cd28 // indirect
 	github.com/lib/pq v1.1.1 // indirect
 	github.com/missinglink/gosmparse v0.0.0-20170628200928-01884c3f2f75
+	github.com/opensearch-project/opensearch-go/v2 v2.3.0
 	github.com/paulmach/go.geojson v1.4.0
+	github.com/prometheus/client_golang v0.9.3
 	github.com/sirupsen/logrus v1.2.0
+	github.com/spf13/cobra v1.0.0
+	github.com/spf13/pflag v1.0.3
 	github.com/spf13/viper v1.4.0
-	github.com/stretchr/testify v1.2.2
+	github.com/stretchr/testify v1.8.2
 	github.com/ziutek/mymysql v1.5.4 // indirect
-	golang.org/x/sync v0.0.0-20181221193216-37e7f081c4d4
+	go.etcd.io/bbolt v1.3.5
+	golang.org/x/sync v0.0.0-20220722155255-886fb9371eb4
 	gopkg.in/olivere/elastic.v3 v3.0.75
 	gotest.tools v2.2.0+incompatible
 )
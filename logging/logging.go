@@ -0,0 +1,101 @@
+// Package logging builds the logger shared by the importer, the parser,
+// the stores and the API, and passes loggers tagged with request and
+// import run ids through contexts.
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/maddevsio/ariadna/config"
+	"github.com/sirupsen/logrus"
+)
+
+// Fields tagging log lines
+const (
+	// RunField is the id of an import run
+	RunField = "run_id"
+	// RequestField is the id of an API request
+	RequestField = "request_id"
+)
+
+// RequestIDHeader carries the request id. An id set by a proxy is kept,
+// the API returns it in the response.
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength limits ids accepted from clients
+const maxRequestIDLength = 128
+
+// New returns a logger configured by c. A log file is opened for
+// appending and stays open for the life of the process.
+func New(c config.Log) (*logrus.Logger, error) {
+	level, err := logrus.ParseLevel(c.Level)
+	if err != nil {
+		return nil, err
+	}
+	var out io.Writer
+	switch c.Output {
+	case config.LogOutputStdout:
+		out = os.Stdout
+	case config.LogOutputStderr, "":
+		out = os.Stderr
+	default:
+		f, err := os.OpenFile(c.Output, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, err
+		}
+		out = f
+	}
+	l := logrus.New()
+	l.SetLevel(level)
+	l.SetOutput(out)
+	if c.Format == config.LogFormatJSON {
+		l.SetFormatter(&logrus.JSONFormatter{})
+	} else {
+		l.SetFormatter(&logrus.TextFormatter{FullTimestamp: true})
+	}
+	return l, nil
+}
+
+// NewID returns a random id for a request or an import run
+func NewID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(b)
+}
+
+type contextKey struct{}
+
+// NewContext returns a context carrying the logger
+func NewContext(ctx context.Context, l logrus.FieldLogger) context.Context {
+	return context.WithValue(ctx, contextKey{}, l)
+}
+
+// FromContext returns the logger carried by ctx or fallback if there is
+// none
+func FromContext(ctx context.Context, fallback logrus.FieldLogger) logrus.FieldLogger {
+	if l, ok := ctx.Value(contextKey{}).(logrus.FieldLogger); ok {
+		return l
+	}
+	return fallback
+}
+
+// RequestID tags the request with an id and passes a logger adding it to
+// every line through the request context
+func RequestID(l logrus.FieldLogger, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if id == "" || len(id) > maxRequestIDLength {
+			id = NewID()
+		}
+		w.Header().Set(RequestIDHeader, id)
+		ctx := NewContext(r.Context(), l.WithField(RequestField, id))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -0,0 +1,57 @@
+package logging
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/maddevsio/ariadna/config"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logging")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "ariadna.log")
+	l, err := New(config.Log{Level: "warn", Format: config.LogFormatJSON, Output: path})
+	require.NoError(t, err)
+	l.Info("skipped")
+	l.WithField(RunField, "abc").Warn("kept")
+
+	data, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	var line map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &line))
+	assert.Equal(t, "kept", line["msg"])
+	assert.Equal(t, "abc", line[RunField])
+}
+
+func TestRequestID(t *testing.T) {
+	base := logrus.New()
+	var id interface{}
+	h := RequestID(base, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id = FromContext(r.Context(), nil).(*logrus.Entry).Data[RequestField]
+	}))
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/api/search/Чуй", nil)
+	r.Header.Set(RequestIDHeader, "from-proxy")
+	h.ServeHTTP(w, r)
+	assert.Equal(t, "from-proxy", id)
+	assert.Equal(t, "from-proxy", w.Header().Get(RequestIDHeader))
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/search/Чуй", nil))
+	assert.Len(t, id, 16)
+	assert.Equal(t, id, w.Header().Get(RequestIDHeader))
+
+	assert.Equal(t, base, FromContext(context.Background(), base))
+}
@@ -0,0 +1,162 @@
+// Package metrics defines prometheus metrics of the importer, the stores
+// and the API. Store and API metrics are registered in the default
+// registry, import metrics are gathered by Import.
+package metrics
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/client_golang/prometheus/push"
+)
+
+const namespace = "ariadna"
+
+// Import metrics are registered in their own registry, only import
+// commands expose them
+var (
+	StageDuration = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "import",
+		Name:      "stage_duration_seconds",
+		Help:      "Duration of the last run of an import stage.",
+	}, []string{"stage"})
+	DocumentsIndexed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "import",
+		Name:      "documents_indexed_total",
+		Help:      "Documents written to the store by layer.",
+	}, []string{"layer"})
+	BulkFailures = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "import",
+		Name:      "bulk_failures_total",
+		Help:      "Bulk writes the store failed.",
+	})
+	importSuccess = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "import",
+		Name:      "success",
+		Help:      "1 if the last import succeeded, 0 if it failed.",
+	})
+	lastSuccess = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "import",
+		Name:      "last_success_timestamp_seconds",
+		Help:      "Unix time of the last successful import.",
+	})
+)
+
+var (
+	importRegistry = prometheus.NewRegistry()
+	// the result is exported only once it is known, so a running or
+	// unrelated process does not report a failed import
+	registerResult sync.Once
+)
+
+// Import gathers metrics of the default registry and the import metrics,
+// import commands serve and push it
+var Import prometheus.Gatherer = prometheus.Gatherers{prometheus.DefaultGatherer, importRegistry}
+
+func init() {
+	importRegistry.MustRegister(StageDuration, DocumentsIndexed, BulkFailures)
+}
+
+// ImportDone records the result of an import, err is the error it failed
+// with
+func ImportDone(err error) {
+	registerResult.Do(func() {
+		importRegistry.MustRegister(importSuccess, lastSuccess)
+	})
+	if err != nil {
+		importSuccess.Set(0)
+		return
+	}
+	importSuccess.Set(1)
+	lastSuccess.SetToCurrentTime()
+}
+
+// Elasticsearch metrics
+var (
+	ElasticDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "elastic",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of requests to the cluster by operation.",
+		Buckets:   []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60},
+	}, []string{"operation"})
+	ElasticErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "elastic",
+		Name:      "request_errors_total",
+		Help:      "Requests to the cluster which failed or were rejected by operation.",
+	}, []string{"operation"})
+)
+
+// API metrics
+var (
+	HTTPRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "API requests by endpoint and status code.",
+	}, []string{"endpoint", "code"})
+	HTTPDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of API requests by endpoint.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"endpoint"})
+	HTTPResults = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "results",
+		Help:      "Number of results returned by endpoint.",
+		Buckets:   []float64{0, 1, 2, 5, 10, 20, 50, 100},
+	}, []string{"endpoint"})
+)
+
+// shutdownTimeout limits waiting for a scrape in progress on shutdown
+const shutdownTimeout = 5 * time.Second
+
+// Handler serves metrics in the prometheus text format
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// Serve serves metrics gathered by g at /metrics on addr until ctx is
+// canceled
+func Serve(ctx context.Context, addr string, g prometheus.Gatherer) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(g, promhttp.HandlerOpts{}))
+	srv := &http.Server{Addr: addr, Handler: mux}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	errs := make(chan error, 1)
+	go func() {
+		errs <- srv.Serve(ln)
+	}()
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	return srv.Shutdown(shutdownCtx)
+}
+
+// Push replaces metrics of the job on the pushgateway at url with those
+// gathered by g, it is used by commands which exit before being scraped
+func Push(url, job string, g prometheus.Gatherer) error {
+	return push.New(url, job).Gatherer(g).Push()
+}
@@ -0,0 +1,41 @@
+package metrics
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func gathered(t *testing.T, g prometheus.Gatherer) map[string]float64 {
+	families, err := g.Gather()
+	require.NoError(t, err)
+	values := make(map[string]float64)
+	for _, f := range families {
+		for _, m := range f.GetMetric() {
+			if m.GetGauge() != nil {
+				values[f.GetName()] = m.GetGauge().GetValue()
+			}
+		}
+	}
+	return values
+}
+
+func TestImportDone(t *testing.T) {
+	assert.NotContains(t, gathered(t, Import), "ariadna_import_success")
+
+	ImportDone(errors.New("failed"))
+	values := gathered(t, Import)
+	assert.Equal(t, float64(0), values["ariadna_import_success"])
+	assert.Equal(t, float64(0), values["ariadna_import_last_success_timestamp_seconds"])
+
+	ImportDone(nil)
+	values = gathered(t, Import)
+	assert.Equal(t, float64(1), values["ariadna_import_success"])
+	assert.NotZero(t, values["ariadna_import_last_success_timestamp_seconds"])
+
+	// the API and update expose the default registry only
+	assert.NotContains(t, gathered(t, prometheus.DefaultGatherer), "ariadna_import_success")
+}
@@ -1,17 +1,42 @@
 package model
 
+// OSM element types
+const (
+	NodeType = "node"
+	WayType  = "way"
+)
+
+// Layers of indexed documents
+const (
+	LayerAddress      = "address"
+	LayerPOI          = "poi"
+	LayerStreet       = "street"
+	LayerIntersection = "intersection"
+	LayerAdmin        = "admin"
+)
+
 type Address struct {
+	OSMType      string   `json:"osm_type"`
+	OSMID        int64    `json:"osm_id"`
+	Layer        string   `json:"layer"`
+	Categories   []string `json:"categories,omitempty"`
 	Country      string   `json:"country"`
 	City         string   `json:"city"`
 	Village      string   `json:"village"`
 	Town         string   `json:"town"`
 	District     string   `json:"district"`
+	Quarter      string   `json:"quarter"`
 	Prefix       string   `json:"prefix"`
 	Street       string   `json:"street"`
 	HouseNumber  string   `json:"housenumber"`
+	Postcode     string   `json:"postcode"`
 	Name         string   `json:"name"`
 	Intersection bool     `json:"intersection"`
+	Streets      []string `json:"streets,omitempty"`
+	NodeIDs      []int64  `json:"node_ids,omitempty"`
 	Location     Location `json:"location"`
+
+	Tags map[string]string `json:"tags,omitempty"`
 }
 type Location struct {
 	Lat float64 `json:"lat"`
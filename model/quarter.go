@@ -0,0 +1,30 @@
+package model
+
+import "strings"
+
+var quarterPrefixes = map[string]bool{
+	"микрорайон": true,
+	"мкр":        true,
+	"мкр.":       true,
+	"мкрн":       true,
+	"мкрн.":      true,
+	"ж/м":        true,
+	"жилмассив":  true,
+}
+
+// IsQuarterPrefix reports whether the word denotes a microdistrict, like "мкр"
+func IsQuarterPrefix(word string) bool {
+	return quarterPrefixes[strings.ToLower(strings.TrimRight(word, ","))]
+}
+
+// TrimQuarterPrefix removes microdistrict designations from the name,
+// so "мкр Джал" and "микрорайон Джал" both become "Джал"
+func TrimQuarterPrefix(name string) string {
+	var words []string
+	for _, word := range strings.Fields(name) {
+		if !IsQuarterPrefix(word) {
+			words = append(words, word)
+		}
+	}
+	return strings.Join(words, " ")
+}
@@ -0,0 +1,35 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrimQuarterPrefix(t *testing.T) {
+	cases := map[string]string{
+		"мкр Джал":          "Джал",
+		"мкр. Джал":         "Джал",
+		"Мкрн Асанбай":      "Асанбай",
+		"микрорайон Джал":   "Джал",
+		"ж/м Ак-Ордо":       "Ак-Ордо",
+		"жилмассив Ак-Ордо": "Ак-Ордо",
+		"Джал мкр":          "Джал",
+		"мкр, Тунгуч":       "Тунгуч",
+		"Магистраль":        "Магистраль",
+		"мкр":               "",
+		"":                  "",
+	}
+	for name, want := range cases {
+		assert.Equal(t, want, TrimQuarterPrefix(name), name)
+	}
+}
+
+func TestIsQuarterPrefix(t *testing.T) {
+	for _, word := range []string{"мкр", "МКР", "мкр.", "мкр,", "мкрн", "микрорайон", "ж/м", "жилмассив"} {
+		assert.True(t, IsQuarterPrefix(word), word)
+	}
+	for _, word := range []string{"Джал", "улица", "ж", "м", "мкрр", ""} {
+		assert.False(t, IsQuarterPrefix(word), word)
+	}
+}
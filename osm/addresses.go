@@ -1,55 +1,82 @@
 package osm
 
 import (
-	"bytes"
-	"fmt"
+	"context"
+
+	"github.com/maddevsio/ariadna/metrics"
+	"github.com/maddevsio/ariadna/model"
+	"github.com/maddevsio/ariadna/store"
+	"github.com/missinglink/gosmparse"
 )
 
-func (i *Importer) waysToElastic() error {
+func (i *Importer) waysToElastic(ctx context.Context) error {
 	i.logger.Info("started to search ways")
-	buf, err := i.getWays()
+	b, err := i.getWays(ctx)
 	if err != nil {
 		return err
 	}
 	i.logger.Info("ways found")
-	return i.e.BulkWrite(buf)
+	return i.bulkWrite(ctx, b)
 }
-func (i *Importer) getWays() (bytes.Buffer, error) {
-	var buf bytes.Buffer
-	for wayID, node := range i.handler.Ways {
-		data, err := i.wayToJSON(node)
-		if err != nil {
-			return buf, err
+func (i *Importer) getWays(ctx context.Context) (*batch, error) {
+	var (
+		b   batch
+		err error
+	)
+	i.handler.Ways.Range(func(way gosmparse.Way) bool {
+		if err = ctx.Err(); err != nil {
+			return false
 		}
-		meta := []byte(fmt.Sprintf(`{ "index": { "_id": "%d" } }%s`, wayID, "\n"))
-		data = append(data, "\n"...)
-		buf.Grow(len(meta) + len(data))
-		buf.Write(meta)
-		buf.Write(data)
-	}
-	return buf, nil
+		b.add(docID("w", way.ID), i.wayToAddress(way))
+		return true
+	})
+	return &b, err
 }
-func (i *Importer) nodesToElastic() error {
+func (i *Importer) nodesToElastic(ctx context.Context) error {
 	i.logger.Info("started to search nodes")
-	buf, err := i.getNodes()
+	b, err := i.getNodes(ctx)
 	if err != nil {
 		return err
 	}
 	i.logger.Info("nodes searched")
-	return i.e.BulkWrite(buf)
+	return i.bulkWrite(ctx, b)
 }
-func (i *Importer) getNodes() (bytes.Buffer, error) {
-	var buf bytes.Buffer
-	for nodeID, node := range i.handler.FilteredNodes {
-		data, err := i.nodeToJSON(node)
-		if err != nil {
-			return buf, err
+func (i *Importer) getNodes(ctx context.Context) (*batch, error) {
+	var (
+		b   batch
+		err error
+	)
+	i.handler.FilteredNodes.Range(func(node gosmparse.Node) bool {
+		if err = ctx.Err(); err != nil {
+			return false
 		}
-		meta := []byte(fmt.Sprintf(`{ "index": { "_id": "%d" } }%s`, nodeID, "\n"))
-		data = append(data, "\n"...)
-		buf.Grow(len(meta) + len(data))
-		buf.Write(meta)
-		buf.Write(data)
+		b.add(docID("n", node.ID), i.nodeToAddress(node))
+		return true
+	})
+	return &b, err
+}
+
+// batch holds documents of a bulk write with their number per layer
+type batch struct {
+	docs   []store.Document
+	layers map[string]uint64
+}
+
+func (b *batch) add(id string, a *model.Address) {
+	if b.layers == nil {
+		b.layers = make(map[string]uint64)
+	}
+	b.docs = append(b.docs, store.Document{ID: id, Address: a})
+	b.layers[a.Layer]++
+}
+
+// bulkWrite writes the batch to the created index and counts its documents
+// as indexed
+func (i *Importer) bulkWrite(ctx context.Context, b *batch) error {
+	if err := i.sink.BulkWrite(ctx, b.docs); err != nil {
+		metrics.BulkFailures.Inc()
+		return err
 	}
-	return buf, nil
+	i.stats.index(b.layers)
+	return nil
 }
@@ -0,0 +1,189 @@
+package osm
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	geo "github.com/kellydunn/golang-geo"
+	"github.com/maddevsio/ariadna/metrics"
+	geojson "github.com/paulmach/go.geojson"
+)
+
+// Types of exported boundaries
+const (
+	boundaryCountry  = "country"
+	boundaryCity     = "city"
+	boundaryDistrict = "district"
+)
+
+// boundaries returns country, city and district polygons the importer
+// built. Properties hold the name, the type and names of enclosing
+// boundaries.
+func (i *Importer) boundaries() *geojson.FeatureCollection {
+	fc := geojson.NewFeatureCollection()
+	add := func(geom *geo.Polygon, props map[string]interface{}) {
+		if ring := polygonRing(geom); ring != nil {
+			f := geojson.NewPolygonFeature([][][]float64{ring})
+			f.Properties = props
+			fc.AddFeature(f)
+		}
+	}
+	for _, c := range i.countries {
+		add(c.geom, map[string]interface{}{"type": boundaryCountry, "name": c.name})
+		for _, town := range c.towns {
+			add(town.geom, map[string]interface{}{
+				"type": boundaryCity, "name": town.name, "place": town.placeType,
+				"country": c.name,
+			})
+			for _, d := range town.districts {
+				add(d.geom, map[string]interface{}{
+					"type": boundaryDistrict, "name": d.name,
+					"city": town.name, "country": c.name,
+				})
+			}
+		}
+	}
+	return fc
+}
+
+// polygonRing returns a closed ring of [lon, lat] points or nil if the
+// polygon has too few points
+func polygonRing(p *geo.Polygon) [][]float64 {
+	points := p.Points()
+	if len(points) < 3 {
+		return nil
+	}
+	ring := make([][]float64, 0, len(points)+1)
+	for _, point := range points {
+		ring = append(ring, []float64{point.Lng(), point.Lat()})
+	}
+	first, last := ring[0], ring[len(ring)-1]
+	if first[0] != last[0] || first[1] != last[1] {
+		ring = append(ring, first)
+	}
+	return ring
+}
+
+// writeBoundaries writes boundaries to path readable by everyone. The file
+// is replaced atomically, so the API never serves a partially written one.
+func (i *Importer) writeBoundaries(path string) error {
+	data, err := json.Marshal(i.boundaries())
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	// TempFile creates the file readable by the owner only, the API may run
+	// as another user
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// boundariesHandler serves the file written by the last import. The type
+// parameter keeps only countries, cities or districts.
+func (i *Importer) boundariesHandler(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	kind := r.URL.Query().Get("type")
+	switch kind {
+	case "", boundaryCountry, boundaryCity, boundaryDistrict:
+	default:
+		writeJSON(w, http.StatusBadRequest, BadRequest{Error: "type must be country, city or district"})
+		return
+	}
+	path := i.config.BoundariesFile
+	if path == "" {
+		writeJSON(w, http.StatusNotFound, BadRequest{Error: "boundaries export is disabled"})
+		return
+	}
+	b, err := i.boundariesCache.get(path, kind)
+	if os.IsNotExist(err) {
+		writeJSON(w, http.StatusNotFound, BadRequest{Error: "boundaries are not imported yet"})
+		return
+	}
+	if err != nil {
+		i.log(r.Context()).Errorf("reading boundaries failed: %v", err)
+		writeJSON(w, http.StatusInternalServerError, BadRequest{Error: "reading boundaries failed"})
+		return
+	}
+	metrics.HTTPResults.WithLabelValues(endpointBoundaries).Observe(float64(b.features))
+	w.Header().Set("Content-Type", "application/geo+json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(b.data)
+}
+
+// boundariesCache keeps the boundaries file encoded per type, so requests
+// do not parse it. It is reloaded when the file changes.
+type boundariesCache struct {
+	mu      sync.Mutex
+	modTime time.Time
+	size    int64
+	byType  map[string]encodedBoundaries
+}
+
+// encodedBoundaries is a feature collection ready to be served
+type encodedBoundaries struct {
+	data     []byte
+	features int
+}
+
+// get returns boundaries of the kind, all of them if kind is empty
+func (c *boundariesCache) get(path, kind string) (encodedBoundaries, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return encodedBoundaries{}, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.byType == nil || !info.ModTime().Equal(c.modTime) || info.Size() != c.size {
+		byType, err := loadBoundaries(path)
+		if err != nil {
+			return encodedBoundaries{}, err
+		}
+		c.byType, c.modTime, c.size = byType, info.ModTime(), info.Size()
+	}
+	return c.byType[kind], nil
+}
+
+// loadBoundaries reads the file and encodes it for every type parameter
+func loadBoundaries(path string) (map[string]encodedBoundaries, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	fc, err := geojson.UnmarshalFeatureCollection(data)
+	if err != nil {
+		return nil, err
+	}
+	byType := map[string]encodedBoundaries{"": {data: data, features: len(fc.Features)}}
+	for _, kind := range []string{boundaryCountry, boundaryCity, boundaryDistrict} {
+		filtered := geojson.NewFeatureCollection()
+		for _, f := range fc.Features {
+			if f.Properties["type"] == kind {
+				filtered.AddFeature(f)
+			}
+		}
+		encoded, err := json.Marshal(filtered)
+		if err != nil {
+			return nil, err
+		}
+		byType[kind] = encodedBoundaries{data: encoded, features: len(filtered.Features)}
+	}
+	return byType, nil
+}
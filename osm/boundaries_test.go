@@ -0,0 +1,116 @@
+package osm
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	geo "github.com/kellydunn/golang-geo"
+	"github.com/maddevsio/ariadna/config"
+	"github.com/maddevsio/ariadna/store"
+	geojson "github.com/paulmach/go.geojson"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func square(lat, lon, size float64) *geo.Polygon {
+	return geo.NewPolygon([]*geo.Point{
+		geo.NewPoint(lat, lon), geo.NewPoint(lat+size, lon),
+		geo.NewPoint(lat+size, lon+size), geo.NewPoint(lat, lon+size),
+	})
+}
+
+func TestBoundaries(t *testing.T) {
+	dir, err := ioutil.TempDir("", "boundaries")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	i := &Importer{
+		config: &config.Ariadna{BoundariesFile: filepath.Join(dir, "boundaries.geojson")},
+		logger: logrus.New(),
+		countries: []country{{
+			name: "Кыргызстан",
+			geom: square(40, 70, 5),
+			towns: []city{{
+				name: "Бишкек", placeType: "city", geom: square(42.8, 74.5, 0.2),
+				districts: []district{{name: "Ленинский район", geom: square(42.8, 74.5, 0.1)}},
+			}},
+		}},
+	}
+	get := func(target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		i.boundariesHandler(w, httptest.NewRequest(http.MethodGet, target, nil), nil)
+		return w
+	}
+	assert.Equal(t, http.StatusNotFound, get("/api/boundaries").Code)
+
+	require.NoError(t, i.writeBoundaries(i.config.BoundariesFile))
+	info, err := os.Stat(i.config.BoundariesFile)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0644), info.Mode().Perm())
+	w := get("/api/boundaries?type=city")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/geo+json", w.Header().Get("Content-Type"))
+	fc, err := geojson.UnmarshalFeatureCollection(w.Body.Bytes())
+	require.NoError(t, err)
+	require.Len(t, fc.Features, 1)
+	f := fc.Features[0]
+	assert.Equal(t, "Бишкек", f.Properties["name"])
+	assert.Equal(t, "Кыргызстан", f.Properties["country"])
+	ring := f.Geometry.Polygon[0]
+	assert.Len(t, ring, 5)
+	assert.Equal(t, []float64{74.5, 42.8}, ring[0])
+	assert.Equal(t, ring[0], ring[4])
+
+	fc, err = geojson.UnmarshalFeatureCollection(get("/api/boundaries").Body.Bytes())
+	require.NoError(t, err)
+	assert.Len(t, fc.Features, 3)
+	assert.Equal(t, http.StatusBadRequest, get("/api/boundaries?type=street").Code)
+
+	// the file is read again once the next import replaces it
+	i.countries[0].towns[0].districts = nil
+	require.NoError(t, i.writeBoundaries(i.config.BoundariesFile))
+	fc, err = geojson.UnmarshalFeatureCollection(get("/api/boundaries").Body.Bytes())
+	require.NoError(t, err)
+	assert.Len(t, fc.Features, 2)
+	fc, err = geojson.UnmarshalFeatureCollection(get("/api/boundaries?type=district").Body.Bytes())
+	require.NoError(t, err)
+	assert.Empty(t, fc.Features)
+}
+
+// swappingStore is a store.Store which serves the created index
+type swappingStore struct {
+	store.Store
+}
+
+func (swappingStore) SwapAlias(ctx context.Context) error { return nil }
+
+func (swappingStore) Prune(ctx context.Context, keep int) ([]string, error) { return nil, nil }
+
+func TestDoneWritesBoundaries(t *testing.T) {
+	dir, err := ioutil.TempDir("", "boundaries")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "boundaries.geojson")
+	i := &Importer{
+		config:    &config.Ariadna{BoundariesFile: path},
+		logger:    logrus.New(),
+		stats:     newStats(),
+		countries: []country{{name: "Кыргызстан", geom: square(40, 70, 5)}},
+	}
+
+	// an export does not replace boundaries of the served index
+	i.sink = swappingStore{}
+	require.NoError(t, i.Done())
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+
+	i.store = swappingStore{}
+	require.NoError(t, i.Done())
+	_, err = os.Stat(path)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,49 @@
+package osm
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// anyValue matches every value of a tag in a category selector
+const anyValue = "*"
+
+// taxonomy maps tag key and value to the categories they belong to
+type taxonomy map[string]map[string][]string
+
+func newTaxonomy(categories map[string][]string) (taxonomy, error) {
+	t := make(taxonomy)
+	for category, selectors := range categories {
+		for _, selector := range selectors {
+			kv := strings.SplitN(selector, "=", 2)
+			if len(kv) != 2 || kv[0] == "" || kv[1] == "" {
+				return nil, fmt.Errorf("category %s: invalid selector %q, want key=value", category, selector)
+			}
+			if t[kv[0]] == nil {
+				t[kv[0]] = make(map[string][]string)
+			}
+			t[kv[0]][kv[1]] = append(t[kv[0]][kv[1]], category)
+		}
+	}
+	return t, nil
+}
+
+// categories returns sorted categories matching the tags
+func (t taxonomy) categories(tags map[string]string) []string {
+	var result []string
+	for key, value := range tags {
+		values, ok := t[key]
+		if !ok {
+			continue
+		}
+		result = append(result, values[value]...)
+		result = append(result, values[anyValue]...)
+	}
+	if len(result) == 0 {
+		return nil
+	}
+	result = uniqString(result)
+	sort.Strings(result)
+	return result
+}
@@ -0,0 +1,36 @@
+package osm
+
+import (
+	"testing"
+
+	"github.com/maddevsio/ariadna/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTaxonomy(t *testing.T) {
+	tx, err := newTaxonomy(map[string][]string{
+		"pharmacy": {"amenity=pharmacy", "healthcare=pharmacy"},
+		"shop":     {"shop=*"},
+		"grocery":  {"shop=supermarket"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"pharmacy"}, tx.categories(map[string]string{
+		"amenity":    "pharmacy",
+		"healthcare": "pharmacy",
+		"name":       "Неман",
+	}))
+	assert.Equal(t, []string{"grocery", "shop"}, tx.categories(map[string]string{"shop": "supermarket"}))
+	assert.Nil(t, tx.categories(map[string]string{"amenity": "bench"}))
+
+	_, err = newTaxonomy(map[string][]string{"broken": {"amenity"}})
+	assert.Error(t, err)
+}
+
+func TestLayer(t *testing.T) {
+	pharmacy := map[string]string{"amenity": "pharmacy", "addr:housenumber": "120"}
+	assert.Equal(t, model.LayerPOI, layer(pharmacy, []string{"pharmacy"}))
+	assert.Equal(t, model.LayerAddress, layer(map[string]string{"addr:housenumber": "120"}, nil))
+	assert.Equal(t, model.LayerStreet, layer(map[string]string{"highway": "residential"}, nil))
+	assert.Equal(t, model.LayerAdmin, layer(map[string]string{"place": "city"}, nil))
+}
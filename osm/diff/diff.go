@@ -0,0 +1,221 @@
+// Package diff reads OSM change files (.osc) published by replication
+// services like planet.openstreetmap.org and geofabrik
+package diff
+
+import (
+	"compress/gzip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/missinglink/gosmparse"
+)
+
+// Change is the final state of elements touched by a diff. When an element
+// is changed several times only its last version is kept.
+type Change struct {
+	// Nodes, Ways and Relations were created or modified
+	Nodes     []gosmparse.Node
+	Ways      []gosmparse.Way
+	Relations []gosmparse.Relation
+	// Deleted elements ids
+	DeletedNodes     []int64
+	DeletedWays      []int64
+	DeletedRelations []int64
+	// Timestamp is the latest timestamp of the elements, the data is
+	// current up to it. It is zero if elements have no timestamps.
+	Timestamp time.Time
+}
+
+type (
+	osmChange struct {
+		Actions []action `xml:",any"`
+	}
+	action struct {
+		XMLName   xml.Name
+		Nodes     []node     `xml:"node"`
+		Ways      []way      `xml:"way"`
+		Relations []relation `xml:"relation"`
+	}
+	tag struct {
+		Key   string `xml:"k,attr"`
+		Value string `xml:"v,attr"`
+	}
+	node struct {
+		ID        int64     `xml:"id,attr"`
+		Timestamp time.Time `xml:"timestamp,attr"`
+		Lat       float64   `xml:"lat,attr"`
+		Lon       float64   `xml:"lon,attr"`
+		Tags      []tag     `xml:"tag"`
+	}
+	way struct {
+		ID        int64     `xml:"id,attr"`
+		Timestamp time.Time `xml:"timestamp,attr"`
+		Refs      []struct {
+			Ref int64 `xml:"ref,attr"`
+		} `xml:"nd"`
+		Tags []tag `xml:"tag"`
+	}
+	relation struct {
+		ID        int64     `xml:"id,attr"`
+		Timestamp time.Time `xml:"timestamp,attr"`
+		Members   []struct {
+			Type string `xml:"type,attr"`
+			Ref  int64  `xml:"ref,attr"`
+			Role string `xml:"role,attr"`
+		} `xml:"member"`
+		Tags []tag `xml:"tag"`
+	}
+)
+
+var memberTypes = map[string]gosmparse.MemberType{
+	"node":     gosmparse.NodeType,
+	"way":      gosmparse.WayType,
+	"relation": gosmparse.RelationType,
+}
+
+// Open reads the change file at path, gzipped if it ends with .gz
+func Open(path string) (*Change, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var r io.Reader = f
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		r = gz
+	}
+	return Parse(r)
+}
+
+// Parse reads an osmChange document
+func Parse(r io.Reader) (*Change, error) {
+	var doc osmChange
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, err
+	}
+	var (
+		nodes     = make(map[int64]*gosmparse.Node)
+		ways      = make(map[int64]*gosmparse.Way)
+		relations = make(map[int64]*gosmparse.Relation)
+		latest    time.Time
+	)
+	seen := func(ts time.Time) {
+		if ts.After(latest) {
+			latest = ts
+		}
+	}
+	for _, a := range doc.Actions {
+		var deleted bool
+		switch a.XMLName.Local {
+		case "create", "modify":
+		case "delete":
+			deleted = true
+		default:
+			return nil, fmt.Errorf("unknown action %q", a.XMLName.Local)
+		}
+		for _, n := range a.Nodes {
+			seen(n.Timestamp)
+			nodes[n.ID] = nil
+			if !deleted {
+				nodes[n.ID] = &gosmparse.Node{ID: n.ID, Lat: n.Lat, Lon: n.Lon, Tags: tagMap(n.Tags)}
+			}
+		}
+		for _, w := range a.Ways {
+			seen(w.Timestamp)
+			ways[w.ID] = nil
+			if !deleted {
+				item := &gosmparse.Way{ID: w.ID, Tags: tagMap(w.Tags)}
+				for _, nd := range w.Refs {
+					item.NodeIDs = append(item.NodeIDs, nd.Ref)
+				}
+				ways[w.ID] = item
+			}
+		}
+		for _, r := range a.Relations {
+			seen(r.Timestamp)
+			relations[r.ID] = nil
+			if !deleted {
+				item := &gosmparse.Relation{ID: r.ID, Tags: tagMap(r.Tags)}
+				for _, m := range r.Members {
+					item.Members = append(item.Members, gosmparse.RelationMember{
+						ID:   m.Ref,
+						Type: memberTypes[m.Type],
+						Role: m.Role,
+					})
+				}
+				relations[r.ID] = item
+			}
+		}
+	}
+	// elements are sorted by id, so the change does not depend on map order
+	c := &Change{Timestamp: latest.UTC()}
+	for _, id := range nodeIDs(nodes) {
+		if n := nodes[id]; n != nil {
+			c.Nodes = append(c.Nodes, *n)
+		} else {
+			c.DeletedNodes = append(c.DeletedNodes, id)
+		}
+	}
+	for _, id := range wayIDs(ways) {
+		if w := ways[id]; w != nil {
+			c.Ways = append(c.Ways, *w)
+		} else {
+			c.DeletedWays = append(c.DeletedWays, id)
+		}
+	}
+	for _, id := range relationIDs(relations) {
+		if r := relations[id]; r != nil {
+			c.Relations = append(c.Relations, *r)
+		} else {
+			c.DeletedRelations = append(c.DeletedRelations, id)
+		}
+	}
+	return c, nil
+}
+
+func tagMap(tags []tag) map[string]string {
+	m := make(map[string]string, len(tags))
+	for _, t := range tags {
+		m[t.Key] = t.Value
+	}
+	return m
+}
+
+func nodeIDs(m map[int64]*gosmparse.Node) []int64 {
+	ids := make([]int64, 0, len(m))
+	for id := range m {
+		ids = append(ids, id)
+	}
+	return sortIDs(ids)
+}
+
+func wayIDs(m map[int64]*gosmparse.Way) []int64 {
+	ids := make([]int64, 0, len(m))
+	for id := range m {
+		ids = append(ids, id)
+	}
+	return sortIDs(ids)
+}
+
+func relationIDs(m map[int64]*gosmparse.Relation) []int64 {
+	ids := make([]int64, 0, len(m))
+	for id := range m {
+		ids = append(ids, id)
+	}
+	return sortIDs(ids)
+}
+
+func sortIDs(ids []int64) []int64 {
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
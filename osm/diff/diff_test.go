@@ -0,0 +1,49 @@
+package diff
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/missinglink/gosmparse"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const osc = `<?xml version="1.0" encoding="UTF-8"?>
+<osmChange version="0.6" generator="test">
+  <create>
+    <node id="1" version="1" lat="42.87" lon="74.59">
+      <tag k="amenity" v="pharmacy"/>
+      <tag k="name" v="Неман"/>
+    </node>
+    <node id="2" version="1" lat="42.88" lon="74.60"/>
+  </create>
+  <modify>
+    <way id="10" version="3" timestamp="2020-05-01T12:30:00Z">
+      <nd ref="1"/>
+      <nd ref="2"/>
+      <tag k="highway" v="residential"/>
+    </way>
+    <node id="2" version="2" lat="42.89" lon="74.61" timestamp="2020-05-01T12:00:00Z"/>
+  </modify>
+  <delete>
+    <node id="1" version="2" lat="42.87" lon="74.59"/>
+    <way id="11" version="5"/>
+    <relation id="20" version="2"/>
+  </delete>
+</osmChange>`
+
+func TestParse(t *testing.T) {
+	c, err := Parse(strings.NewReader(osc))
+	require.NoError(t, err)
+	assert.Equal(t, []gosmparse.Node{{ID: 2, Lat: 42.89, Lon: 74.61, Tags: map[string]string{}}}, c.Nodes)
+	assert.Equal(t, []int64{1}, c.DeletedNodes)
+	require.Len(t, c.Ways, 1)
+	assert.Equal(t, []int64{1, 2}, c.Ways[0].NodeIDs)
+	assert.Equal(t, "residential", c.Ways[0].Tags["highway"])
+	assert.Equal(t, []int64{11}, c.DeletedWays)
+	assert.Empty(t, c.Relations)
+	assert.Equal(t, []int64{20}, c.DeletedRelations)
+	assert.Equal(t, time.Date(2020, 5, 1, 12, 30, 0, 0, time.UTC), c.Timestamp)
+}
@@ -1,24 +1,30 @@
 package osm
 
 import (
+	"context"
 	"io"
 	"net/http"
 	"os"
 )
 
-func (i *Importer) download() error {
+func (i *Importer) download(ctx context.Context) error {
 	i.logger.Infof("downloading %s", i.config.OSMURL)
-	resp, err := http.Get(i.config.OSMURL)
+	req, err := http.NewRequest(http.MethodGet, i.config.OSMURL, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
+	i.stats.setDownloadSize(resp.ContentLength)
 
 	f, err := os.Create(i.config.OSMFilename)
 	if err != nil {
 		return err
 	}
 	defer f.Close()
-	_, err = io.Copy(f, resp.Body)
+	_, err = io.Copy(f, io.TeeReader(resp.Body, i.stats))
 	return err
 }
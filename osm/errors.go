@@ -0,0 +1,59 @@
+package osm
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Import stages reported in errors
+const (
+	stageDownload    = "download"
+	stageParse       = "parse"
+	stageCreateIndex = "create index"
+	stageCrossroads  = "crossroads"
+	stageNodes       = "nodes"
+	stageWays        = "ways"
+	stageSwapAlias   = "swap alias"
+	stageDeleteOld   = "delete old indices"
+	stageDiff        = "apply diff"
+	stageUpdate      = "update"
+)
+
+// StageError tells which stage of the import failed
+type StageError struct {
+	Stage string
+	Err   error
+}
+
+func (e *StageError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Stage, e.Err)
+}
+
+// ImportError collects errors of import stages running concurrently
+type ImportError struct {
+	mu     sync.Mutex
+	Errors []*StageError
+}
+
+func (e *ImportError) add(stage string, err error) {
+	e.mu.Lock()
+	e.Errors = append(e.Errors, &StageError{Stage: stage, Err: err})
+	e.mu.Unlock()
+}
+
+// err returns nil if no stage failed
+func (e *ImportError) err() error {
+	if len(e.Errors) == 0 {
+		return nil
+	}
+	return e
+}
+
+func (e *ImportError) Error() string {
+	msgs := make([]string, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		msgs = append(msgs, err.Error())
+	}
+	return fmt.Sprintf("%d stage(s) failed: %s", len(e.Errors), strings.Join(msgs, "; "))
+}
@@ -0,0 +1,62 @@
+package osm
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/maddevsio/ariadna/config"
+	"github.com/maddevsio/ariadna/store"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/sync/errgroup"
+)
+
+func TestRunRecordsFailedStages(t *testing.T) {
+	i := &Importer{ctx: context.Background(), stats: newStats()}
+	eg, ctx := errgroup.WithContext(i.ctx)
+	i.eg = eg
+	i.run(ctx, stageNodes, func(context.Context) error {
+		return errors.New("bulk insert failed")
+	})
+	i.run(ctx, stageWays, func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	i.run(ctx, stageCrossroads, func(context.Context) error { return nil })
+	assert.Error(t, i.eg.Wait())
+	assert.EqualError(t, i.errs.err(), "1 stage(s) failed: nodes: bulk insert failed")
+
+	interrupted, cancel := context.WithCancel(context.Background())
+	i = &Importer{ctx: interrupted, stats: newStats()}
+	eg, ctx = errgroup.WithContext(i.ctx)
+	i.eg = eg
+	cancel()
+	i.run(ctx, stageWays, func(ctx context.Context) error { return ctx.Err() })
+	i.eg.Wait()
+	assert.EqualError(t, i.errs.err(), "1 stage(s) failed: ways: context canceled")
+}
+
+// swapFailingSink is a store.Sink which cannot move the alias
+type swapFailingSink struct {
+	store.Sink
+	deleted bool
+}
+
+func (s *swapFailingSink) SwapAlias(ctx context.Context) error {
+	return errors.New("alias update rejected")
+}
+
+func (s *swapFailingSink) DeleteCreatedIndex(ctx context.Context) error {
+	s.deleted = true
+	return nil
+}
+
+func TestDoneDeletesIndexIfSwapFails(t *testing.T) {
+	interrupted, cancel := context.WithCancel(context.Background())
+	cancel()
+	sink := &swapFailingSink{}
+	i := &Importer{ctx: interrupted, config: &config.Ariadna{}, sink: sink, logger: logrus.New(), stats: newStats()}
+	assert.EqualError(t, i.Done(), "swap alias: alias update rejected")
+	assert.True(t, sink.deleted)
+}
@@ -1,87 +1,79 @@
 package handler
 
 import (
-	"strconv"
+	"sort"
 	"sync"
 
+	"github.com/maddevsio/ariadna/config"
 	"github.com/missinglink/gosmparse"
 )
 
-// Handler - Load all elements in to memory
+// NodeWay links a node to a named highway passing through it
+type NodeWay struct {
+	NodeID int64
+	WayID  int64
+}
+
+// Handler - Load all elements in to memory. Its methods are called
+// concurrently by the decoder: nodes and ways are kept in sharded maps,
+// mu guards the rest.
 type Handler struct {
 	mu            *sync.Mutex
-	InvertedIndex map[string][]string
-	Nodes         map[int64]gosmparse.Node
-	FilteredNodes map[int64]gosmparse.Node
-	Ways          map[int64]gosmparse.Way
-	FullWays      map[int64]gosmparse.Way
+	NodeWays      []NodeWay
+	Nodes         *NodeMap
+	FilteredNodes *NodeMap
+	Ways          *WayMap
+	FullWays      *WayMap
 
-	WayNames     map[string]string
+	WayNames     map[int64]string
 	Areas        map[int64]gosmparse.Relation
 	Districts    map[int64]gosmparse.Way
 	Countries    map[int64]gosmparse.Relation
+	PostalCodes  map[int64]gosmparse.Relation
+	Quarters     map[int64]gosmparse.Way
+	QuarterAreas map[int64]gosmparse.Relation
 	highWayTags  map[string]bool
 	areaTags     map[string]bool
 	districtTags map[string]bool
+	quarterTags  map[string]bool
 	addressTags  map[string]string
 }
 
-// New creates new instance of Handler
-func New() *Handler {
+// New creates new instance of Handler which keeps elements matching filters
+func New(filters config.TagFilters) *Handler {
 	h := &Handler{
 		mu:            &sync.Mutex{},
-		Nodes:         make(map[int64]gosmparse.Node),
-		FilteredNodes: make(map[int64]gosmparse.Node),
-		Ways:          make(map[int64]gosmparse.Way),
-		FullWays:      make(map[int64]gosmparse.Way),
-		WayNames:      make(map[string]string),
+		Nodes:         NewNodeMap(),
+		FilteredNodes: NewNodeMap(),
+		Ways:          NewWayMap(),
+		FullWays:      NewWayMap(),
+		WayNames:      make(map[int64]string),
 		Areas:         make(map[int64]gosmparse.Relation),
 		Districts:     make(map[int64]gosmparse.Way),
 		Countries:     make(map[int64]gosmparse.Relation),
-		InvertedIndex: make(map[string][]string),
-	}
-	h.highWayTags = map[string]bool{
-		"motorway":    false,
-		"trunk":       false,
-		"primary":     false,
-		"secondary":   false,
-		"residential": false,
-		"service":     false,
-		"tertiary":    false,
-		"road":        false,
-	}
-	h.areaTags = map[string]bool{
-		"town":    false,
-		"city":    false,
-		"village": false,
-		"hamlet":  false,
-	}
-	h.districtTags = map[string]bool{
-		"neighbourhood": false,
-		"suburb":        false,
-	}
-	h.addressTags = map[string]string{
-		"addr:street":      "addr:housenumber",
-		"amenity":          "name",
-		"building":         "name",
-		"addr:housenumber": "",
-		"shop":             "name",
-		"office":           "name",
-		"public_transport": "name",
-		"cuisine":          "name",
-		"railway":          "name",
-		"sport":            "name",
-		"natural":          "name",
-		"tourism":          "name",
-		"leisure":          "name",
-		"historic":         "name",
-		"man_made":         "name",
-		"landuse":          "name",
-		"waterway":         "name",
-		"aerialway":        "name",
-		"aeroway":          "name",
-		"craft":            "name",
-		"military":         "name",
+		PostalCodes:   make(map[int64]gosmparse.Relation),
+		Quarters:      make(map[int64]gosmparse.Way),
+		QuarterAreas:  make(map[int64]gosmparse.Relation),
+		highWayTags:   make(map[string]bool),
+		areaTags:      make(map[string]bool),
+		districtTags:  make(map[string]bool),
+		quarterTags:   make(map[string]bool),
+		addressTags:   make(map[string]string),
+	}
+	for _, v := range filters.Highway {
+		h.highWayTags[v] = false
+	}
+	for _, v := range filters.Area {
+		h.areaTags[v] = false
+	}
+	for _, v := range filters.District {
+		h.districtTags[v] = false
+	}
+	for _, v := range filters.Quarter {
+		h.quarterTags[v] = false
+	}
+	for k, v := range filters.Address {
+		h.addressTags[k] = v
 	}
 
 	return h
@@ -89,62 +81,87 @@ func New() *Handler {
 
 // ReadNode - called once per node
 func (h *Handler) ReadNode(item gosmparse.Node) {
-	h.mu.Lock()
-	h.Nodes[item.ID] = item
-	for k, v := range h.addressTags {
-		if item.Tags[k] != "" {
-			if v == "" {
-				h.FilteredNodes[item.ID] = item
-			}
-			if v != "" && item.Tags[v] != "" {
-				h.FilteredNodes[item.ID] = item
-			}
-		}
+	h.Nodes.Set(item)
+	if h.IsAddress(item.Tags) {
+		h.FilteredNodes.Set(item)
 	}
-	h.mu.Unlock()
 }
 
 // ReadWay - called once per way
 func (h *Handler) ReadWay(item gosmparse.Way) {
-	h.mu.Lock()
-	defer h.mu.Unlock()
-
-	if _, ok := h.districtTags[item.Tags["place"]]; ok {
-		h.Districts[item.ID] = item
+	h.FullWays.Set(item)
+	if h.IsAddress(item.Tags) {
+		h.Ways.Set(item)
 	}
-	h.FullWays[item.ID] = item
-	for k, v := range h.addressTags {
-		if item.Tags[k] != "" {
-			if v == "" {
-				h.Ways[item.ID] = item
-			}
-			if v != "" && item.Tags[v] != "" {
-				h.Ways[item.ID] = item
-			}
+	_, isDistrict := h.districtTags[item.Tags["place"]]
+	_, isQuarter := h.quarterTags[item.Tags["place"]]
+	if isDistrict || isQuarter {
+		h.mu.Lock()
+		if isDistrict {
+			h.Districts[item.ID] = item
+		}
+		if isQuarter {
+			h.Quarters[item.ID] = item
 		}
+		h.mu.Unlock()
 	}
 
 	if _, ok := h.highWayTags[item.Tags["highway"]]; !ok {
 		return
 	}
 	if item.Tags["addr:street"] != "" && item.Tags["addr:housenumber"] != "" {
-
-		h.Ways[item.ID] = item
+		h.Ways.Set(item)
 	}
 
-	// convert int64 to string
-	var wayIDString = strconv.FormatInt(item.ID, 10)
-
-	if val, ok := item.Tags["addr:street"]; ok {
-		h.WayNames[wayIDString] = val
-	} else if val, ok := item.Tags["name"]; ok {
-		h.WayNames[wayIDString] = val
-	} else {
+	name, ok := item.Tags["addr:street"]
+	if !ok {
+		name, ok = item.Tags["name"]
+	}
+	if !ok {
 		return
 	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.WayNames[item.ID] = name
 	for _, nodeid := range item.NodeIDs {
-		var nodeIDString = strconv.FormatInt(nodeid, 10)
-		h.InvertedIndex[nodeIDString] = append(h.InvertedIndex[nodeIDString], wayIDString)
+		h.NodeWays = append(h.NodeWays, NodeWay{NodeID: nodeid, WayID: item.ID})
+	}
+}
+
+// IsAddress reports whether the tags match the address filters
+func (h *Handler) IsAddress(tags map[string]string) bool {
+	for k, v := range h.addressTags {
+		if tags[k] != "" && (v == "" || tags[v] != "") {
+			return true
+		}
+	}
+	return false
+}
+
+// EachSharedNode calls fn for every node shared by two or more named
+// highways. wayIDs are sorted and unique, fn must not retain the slice.
+func (h *Handler) EachSharedNode(fn func(nodeID int64, wayIDs []int64)) {
+	sort.Slice(h.NodeWays, func(i, j int) bool {
+		a, b := h.NodeWays[i], h.NodeWays[j]
+		if a.NodeID != b.NodeID {
+			return a.NodeID < b.NodeID
+		}
+		return a.WayID < b.WayID
+	})
+	var wayIDs []int64
+	for start := 0; start < len(h.NodeWays); {
+		nodeID := h.NodeWays[start].NodeID
+		wayIDs = wayIDs[:0]
+		end := start
+		for ; end < len(h.NodeWays) && h.NodeWays[end].NodeID == nodeID; end++ {
+			if n := len(wayIDs); n == 0 || wayIDs[n-1] != h.NodeWays[end].WayID {
+				wayIDs = append(wayIDs, h.NodeWays[end].WayID)
+			}
+		}
+		if len(wayIDs) > 1 {
+			fn(nodeID, wayIDs)
+		}
+		start = end
 	}
 }
 
@@ -157,5 +174,11 @@ func (h *Handler) ReadRelation(item gosmparse.Relation) {
 	if _, ok := h.areaTags[item.Tags["place"]]; ok {
 		h.Areas[item.ID] = item
 	}
+	if _, ok := h.quarterTags[item.Tags["place"]]; ok {
+		h.QuarterAreas[item.ID] = item
+	}
+	if item.Tags["boundary"] == "postal_code" {
+		h.PostalCodes[item.ID] = item
+	}
 	h.mu.Unlock()
 }
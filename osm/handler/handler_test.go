@@ -0,0 +1,170 @@
+package handler
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/maddevsio/ariadna/config"
+	"github.com/missinglink/gosmparse"
+	"github.com/stretchr/testify/assert"
+)
+
+// roadGrid returns n horizontal and n vertical named residential roads
+// crossing at n*n nodes
+func roadGrid(n int) []gosmparse.Way {
+	var ways []gosmparse.Way
+	for r := 0; r < n; r++ {
+		h := gosmparse.Way{ID: int64(r + 1), Tags: map[string]string{"highway": "residential", "name": fmt.Sprintf("Улица %d", r)}}
+		v := gosmparse.Way{ID: int64(n + r + 1), Tags: map[string]string{"highway": "residential", "name": fmt.Sprintf("Проспект %d", r)}}
+		for c := 0; c < n; c++ {
+			h.NodeIDs = append(h.NodeIDs, int64(r*n+c+1))
+			v.NodeIDs = append(v.NodeIDs, int64(c*n+r+1))
+		}
+		ways = append(ways, h, v)
+	}
+	return ways
+}
+
+func TestEachSharedNode(t *testing.T) {
+	h := New(config.DefaultTagFilters)
+	for _, way := range roadGrid(3) {
+		h.ReadWay(way)
+	}
+	// a closed way references its first node twice
+	h.ReadWay(gosmparse.Way{ID: 100, NodeIDs: []int64{50, 51, 52, 50}, Tags: map[string]string{"highway": "service", "name": "Двор"}})
+	shared := make(map[int64][]int64)
+	h.EachSharedNode(func(nodeID int64, wayIDs []int64) {
+		shared[nodeID] = append([]int64(nil), wayIDs...)
+	})
+	assert.Len(t, shared, 9)
+	assert.Equal(t, []int64{1, 4}, shared[1])
+	assert.Equal(t, []int64{3, 6}, shared[9])
+	assert.NotContains(t, shared, int64(50))
+}
+
+func TestConcurrentRead(t *testing.T) {
+	h := New(config.DefaultTagFilters)
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				id := int64(w*1000 + i)
+				h.ReadNode(gosmparse.Node{ID: id, Tags: map[string]string{"amenity": "cafe", "name": "Фаиза"}})
+				h.ReadWay(gosmparse.Way{ID: id, NodeIDs: []int64{id, id + 1}, Tags: map[string]string{"highway": "primary", "name": "Чуй"}})
+			}
+		}(w)
+	}
+	wg.Wait()
+	assert.Equal(t, 8000, h.Nodes.Len())
+	assert.Equal(t, 8000, h.FilteredNodes.Len())
+	assert.Equal(t, 8000, h.FullWays.Len())
+	assert.Len(t, h.NodeWays, 16000)
+}
+
+func TestTwoPass(t *testing.T) {
+	h := New(config.DefaultTagFilters)
+	nodes := []gosmparse.Node{
+		{ID: 1}, {ID: 2}, {ID: 3}, {ID: 4}, {ID: 5}, {ID: 6},
+		{ID: 7, Tags: map[string]string{"amenity": "pharmacy", "name": "Неман"}},
+	}
+	ways := []gosmparse.Way{
+		{ID: 10, NodeIDs: []int64{1, 2}, Tags: map[string]string{"highway": "primary", "name": "Чуй"}},
+		{ID: 11, NodeIDs: []int64{3, 4}},
+		{ID: 12, NodeIDs: []int64{5, 6}},
+	}
+	relations := []gosmparse.Relation{{
+		ID:      20,
+		Members: []gosmparse.RelationMember{{ID: 11, Type: gosmparse.WayType}},
+		Tags:    map[string]string{"place": "city", "name": "Бишкек"},
+	}}
+	read := func(r gosmparse.OSMReader) {
+		for _, n := range nodes {
+			r.ReadNode(n)
+		}
+		for _, w := range ways {
+			r.ReadWay(w)
+		}
+		for _, rel := range relations {
+			r.ReadRelation(rel)
+		}
+	}
+	read(h.WaysPass())
+	assert.Equal(t, 0, h.Nodes.Len())
+	read(h.NodesPass())
+	for id, kept := range map[int64]bool{1: true, 2: true, 3: true, 4: true, 5: false, 6: false, 7: false} {
+		_, ok := h.Nodes.Get(id)
+		assert.Equal(t, kept, ok, "node %d", id)
+	}
+	_, ok := h.FilteredNodes.Get(7)
+	assert.True(t, ok)
+	_, ok = h.FullWays.Get(11)
+	assert.True(t, ok)
+	_, ok = h.FullWays.Get(12)
+	assert.False(t, ok)
+}
+
+// BenchmarkIntersections/int64 measures the handler index, the string
+// variant replays the former map[string][]string index for comparison.
+func BenchmarkIntersections(b *testing.B) {
+	ways := roadGrid(300)
+	b.Run("int64", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			h := New(config.DefaultTagFilters)
+			for _, way := range ways {
+				h.ReadWay(way)
+			}
+			shared := 0
+			h.EachSharedNode(func(int64, []int64) { shared++ })
+		}
+	})
+	b.Run("string", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			invertedIndex := make(map[string][]string)
+			wayNames := make(map[string]string)
+			for _, way := range ways {
+				wayID := strconv.FormatInt(way.ID, 10)
+				wayNames[wayID] = way.Tags["name"]
+				for _, nodeID := range way.NodeIDs {
+					key := strconv.FormatInt(nodeID, 10)
+					invertedIndex[key] = append(invertedIndex[key], wayID)
+				}
+			}
+			shared := 0
+			for nodeID, wayIDs := range invertedIndex {
+				unique := make(map[string]bool)
+				for _, id := range wayIDs {
+					unique[id] = true
+				}
+				if len(unique) < 2 {
+					continue
+				}
+				if _, err := strconv.Atoi(nodeID); err == nil {
+					shared++
+				}
+			}
+		}
+	})
+}
+
+func BenchmarkReadNodeParallel(b *testing.B) {
+	h := New(config.DefaultTagFilters)
+	var id int64
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			h.ReadNode(gosmparse.Node{
+				ID:   atomic.AddInt64(&id, 1),
+				Lat:  42.87,
+				Lon:  74.59,
+				Tags: map[string]string{"addr:housenumber": "1"},
+			})
+		}
+	})
+}
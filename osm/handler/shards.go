@@ -0,0 +1,165 @@
+package handler
+
+import (
+	"sync"
+
+	"github.com/missinglink/gosmparse"
+)
+
+// shardCount must be a power of two
+const shardCount = 64
+
+func shardOf(id int64) int {
+	return int(uint64(id) & (shardCount - 1))
+}
+
+// NodeMap is a map of nodes split into independently locked shards, so
+// decoder goroutines storing different nodes rarely wait for each other
+type NodeMap struct {
+	shards [shardCount]struct {
+		sync.RWMutex
+		m map[int64]gosmparse.Node
+	}
+}
+
+// NewNodeMap creates an empty NodeMap
+func NewNodeMap() *NodeMap {
+	m := &NodeMap{}
+	for i := range m.shards {
+		m.shards[i].m = make(map[int64]gosmparse.Node)
+	}
+	return m
+}
+
+// Set stores the node
+func (m *NodeMap) Set(node gosmparse.Node) {
+	s := &m.shards[shardOf(node.ID)]
+	s.Lock()
+	s.m[node.ID] = node
+	s.Unlock()
+}
+
+// Get returns the node by id
+func (m *NodeMap) Get(id int64) (gosmparse.Node, bool) {
+	s := &m.shards[shardOf(id)]
+	s.RLock()
+	node, ok := s.m[id]
+	s.RUnlock()
+	return node, ok
+}
+
+// Delete removes the node by id
+func (m *NodeMap) Delete(id int64) {
+	s := &m.shards[shardOf(id)]
+	s.Lock()
+	delete(s.m, id)
+	s.Unlock()
+}
+
+// Len returns the number of stored nodes
+func (m *NodeMap) Len() int {
+	var n int
+	for i := range m.shards {
+		m.shards[i].RLock()
+		n += len(m.shards[i].m)
+		m.shards[i].RUnlock()
+	}
+	return n
+}
+
+// Range calls fn for every node until fn returns false, like sync.Map.
+// fn must not modify the map.
+func (m *NodeMap) Range(fn func(gosmparse.Node) bool) {
+	for i := range m.shards {
+		m.shards[i].RLock()
+		for _, node := range m.shards[i].m {
+			if !fn(node) {
+				m.shards[i].RUnlock()
+				return
+			}
+		}
+		m.shards[i].RUnlock()
+	}
+}
+
+// WayMap is a map of ways split into independently locked shards
+type WayMap struct {
+	shards [shardCount]struct {
+		sync.RWMutex
+		m map[int64]gosmparse.Way
+	}
+}
+
+// NewWayMap creates an empty WayMap
+func NewWayMap() *WayMap {
+	m := &WayMap{}
+	for i := range m.shards {
+		m.shards[i].m = make(map[int64]gosmparse.Way)
+	}
+	return m
+}
+
+// Set stores the way
+func (m *WayMap) Set(way gosmparse.Way) {
+	s := &m.shards[shardOf(way.ID)]
+	s.Lock()
+	s.m[way.ID] = way
+	s.Unlock()
+}
+
+// Get returns the way by id
+func (m *WayMap) Get(id int64) (gosmparse.Way, bool) {
+	s := &m.shards[shardOf(id)]
+	s.RLock()
+	way, ok := s.m[id]
+	s.RUnlock()
+	return way, ok
+}
+
+// Delete removes the way by id
+func (m *WayMap) Delete(id int64) {
+	s := &m.shards[shardOf(id)]
+	s.Lock()
+	delete(s.m, id)
+	s.Unlock()
+}
+
+// Len returns the number of stored ways
+func (m *WayMap) Len() int {
+	var n int
+	for i := range m.shards {
+		m.shards[i].RLock()
+		n += len(m.shards[i].m)
+		m.shards[i].RUnlock()
+	}
+	return n
+}
+
+// Range calls fn for every way until fn returns false, like sync.Map.
+// fn must not modify the map.
+func (m *WayMap) Range(fn func(gosmparse.Way) bool) {
+	for i := range m.shards {
+		m.shards[i].RLock()
+		for _, way := range m.shards[i].m {
+			if !fn(way) {
+				m.shards[i].RUnlock()
+				return
+			}
+		}
+		m.shards[i].RUnlock()
+	}
+}
+
+// Retain removes ways for which keep returns false
+func (m *WayMap) Retain(keep func(gosmparse.Way) bool) {
+	for i := range m.shards {
+		s := &m.shards[i]
+		s.Lock()
+		for id, way := range s.m {
+			if !keep(way) {
+				delete(s.m, id)
+			}
+		}
+		s.Unlock()
+	}
+}
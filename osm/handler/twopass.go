@@ -0,0 +1,108 @@
+package handler
+
+import (
+	"sort"
+
+	"github.com/missinglink/gosmparse"
+)
+
+type (
+	// waysPass reads everything but nodes
+	waysPass struct {
+		h *Handler
+	}
+	// nodesPass reads only nodes which are referenced by kept elements
+	// or match the address filters
+	nodesPass struct {
+		h      *Handler
+		needed []int64
+	}
+)
+
+// WaysPass returns a reader for the first of two passes over the file.
+// Nodes are skipped, ways and relations are kept as usual.
+func (h *Handler) WaysPass() gosmparse.OSMReader {
+	return waysPass{h: h}
+}
+
+func (p waysPass) ReadNode(gosmparse.Node)              {}
+func (p waysPass) ReadWay(item gosmparse.Way)           { p.h.ReadWay(item) }
+func (p waysPass) ReadRelation(item gosmparse.Relation) { p.h.ReadRelation(item) }
+
+// NodesPass returns a reader for the second pass over the file. It drops
+// ways which are not members of kept relations and keeps only the nodes
+// needed to locate kept elements, so it must be called after the first pass.
+func (h *Handler) NodesPass() gosmparse.OSMReader {
+	relations := h.relations()
+	memberWays := make(map[int64]bool)
+	var needed []int64
+	for _, r := range relations {
+		for _, member := range r.Members {
+			switch member.Type {
+			case gosmparse.WayType:
+				memberWays[member.ID] = true
+			case gosmparse.NodeType:
+				needed = append(needed, member.ID)
+			}
+		}
+	}
+	h.FullWays.Retain(func(way gosmparse.Way) bool {
+		return memberWays[way.ID]
+	})
+	addWays := func(way gosmparse.Way) bool {
+		needed = append(needed, way.NodeIDs...)
+		return true
+	}
+	h.FullWays.Range(addWays)
+	h.Ways.Range(addWays)
+	for _, way := range h.Districts {
+		addWays(way)
+	}
+	for _, way := range h.Quarters {
+		addWays(way)
+	}
+	for _, nw := range h.NodeWays {
+		needed = append(needed, nw.NodeID)
+	}
+	return nodesPass{h: h, needed: uniqInt64(needed)}
+}
+
+func (p nodesPass) ReadNode(item gosmparse.Node) {
+	if p.isNeeded(item.ID) {
+		p.h.Nodes.Set(item)
+	}
+	if p.h.IsAddress(item.Tags) {
+		p.h.FilteredNodes.Set(item)
+	}
+}
+func (p nodesPass) ReadWay(gosmparse.Way)           {}
+func (p nodesPass) ReadRelation(gosmparse.Relation) {}
+
+func (p nodesPass) isNeeded(id int64) bool {
+	i := sort.Search(len(p.needed), func(i int) bool { return p.needed[i] >= id })
+	return i < len(p.needed) && p.needed[i] == id
+}
+
+// relations returns all kept relations
+func (h *Handler) relations() []gosmparse.Relation {
+	var relations []gosmparse.Relation
+	for _, m := range []map[int64]gosmparse.Relation{h.Countries, h.Areas, h.PostalCodes, h.QuarterAreas} {
+		for _, r := range m {
+			relations = append(relations, r)
+		}
+	}
+	return relations
+}
+
+// uniqInt64 sorts ids and removes duplicates in place
+func uniqInt64(ids []int64) []int64 {
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	n := 0
+	for i, id := range ids {
+		if i == 0 || id != ids[n-1] {
+			ids[n] = id
+			n++
+		}
+	}
+	return ids[:n]
+}
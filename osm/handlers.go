@@ -1,9 +1,23 @@
 package osm
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"net/url"
+	"strconv"
 
 	"github.com/julienschmidt/httprouter"
+	"github.com/maddevsio/ariadna/metrics"
+	"github.com/maddevsio/ariadna/store"
+)
+
+const (
+	defaultPlacesRadius = 1000
+	maxPlacesRadius     = 50000
+	defaultPlacesSize   = 10
+	maxPlacesSize       = 100
 )
 
 type BadRequest struct {
@@ -11,11 +25,91 @@ type BadRequest struct {
 }
 
 func (i *Importer) geoCodeHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-	w.Write([]byte{1})
-
+	addresses, err := i.store.Search(r.Context(), ps.ByName("query"))
+	if err != nil {
+		i.log(r.Context()).Errorf("search failed: %v", err)
+		writeJSON(w, http.StatusInternalServerError, BadRequest{Error: "search failed"})
+		return
+	}
+	metrics.HTTPResults.WithLabelValues(endpointSearch).Observe(float64(len(addresses)))
+	writeJSON(w, http.StatusOK, addresses)
 }
 
 func (i *Importer) reverseGeoCodeHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-	w.Write([]byte{1})
+	lat, err := strconv.ParseFloat(ps.ByName("lat"), 64)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, BadRequest{Error: "invalid lat"})
+		return
+	}
+	lon, err := strconv.ParseFloat(ps.ByName("lon"), 64)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, BadRequest{Error: "invalid lon"})
+		return
+	}
+	addresses, err := i.store.Reverse(r.Context(), lat, lon)
+	if err != nil {
+		i.log(r.Context()).Errorf("reverse search failed: %v", err)
+		writeJSON(w, http.StatusInternalServerError, BadRequest{Error: "search failed"})
+		return
+	}
+	metrics.HTTPResults.WithLabelValues(endpointReverse).Observe(float64(len(addresses)))
+	writeJSON(w, http.StatusOK, addresses)
+}
+
+func (i *Importer) placesHandler(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	q, err := placesQuery(r.URL.Query())
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, BadRequest{Error: err.Error()})
+		return
+	}
+	places, err := i.store.Places(r.Context(), q)
+	if err != nil {
+		i.log(r.Context()).Errorf("places search failed: %v", err)
+		writeJSON(w, http.StatusInternalServerError, BadRequest{Error: "search failed"})
+		return
+	}
+	metrics.HTTPResults.WithLabelValues(endpointPlaces).Observe(float64(len(places)))
+	writeJSON(w, http.StatusOK, places)
+}
+
+func placesQuery(v url.Values) (store.PlacesQuery, error) {
+	q := store.PlacesQuery{
+		Category: v.Get("category"),
+		Radius:   defaultPlacesRadius,
+		Size:     defaultPlacesSize,
+	}
+	if q.Category == "" {
+		return q, errors.New("category is required")
+	}
+	var err error
+	if q.Lat, err = strconv.ParseFloat(v.Get("lat"), 64); err != nil {
+		return q, errors.New("invalid lat")
+	}
+	if q.Lon, err = strconv.ParseFloat(v.Get("lon"), 64); err != nil {
+		return q, errors.New("invalid lon")
+	}
+	if s := v.Get("radius"); s != "" {
+		if q.Radius, err = strconv.ParseFloat(s, 64); err != nil || q.Radius <= 0 || q.Radius > maxPlacesRadius {
+			return q, fmt.Errorf("radius must be between 0 and %d meters", maxPlacesRadius)
+		}
+	}
+	if s := v.Get("size"); s != "" {
+		if q.Size, err = strconv.Atoi(s); err != nil || q.Size < 1 || q.Size > maxPlacesSize {
+			return q, fmt.Errorf("size must be between 1 and %d", maxPlacesSize)
+		}
+	}
+	page := 1
+	if s := v.Get("page"); s != "" {
+		if page, err = strconv.Atoi(s); err != nil || page < 1 {
+			return q, errors.New("page must be a positive number")
+		}
+	}
+	q.From = (page - 1) * q.Size
+	return q, nil
+}
 
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
 }
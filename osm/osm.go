@@ -1,32 +1,58 @@
 package osm
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"log"
-	"net/http"
-	"os"
+	"sort"
+	"strings"
+	"time"
 
-	"github.com/julienschmidt/httprouter"
 	geo "github.com/kellydunn/golang-geo"
 	"github.com/maddevsio/ariadna/config"
-	"github.com/maddevsio/ariadna/elastic"
+	"github.com/maddevsio/ariadna/logging"
+	"github.com/maddevsio/ariadna/metrics"
+	"github.com/maddevsio/ariadna/model"
 	"github.com/maddevsio/ariadna/osm/handler"
 	"github.com/maddevsio/ariadna/osm/parser"
+	"github.com/maddevsio/ariadna/store"
 	"github.com/missinglink/gosmparse"
 	"github.com/sirupsen/logrus"
 	"golang.org/x/sync/errgroup"
 )
 
+// cleanupTimeout limits deleting of a partially built index on interrupt
+const cleanupTimeout = 30 * time.Second
+
+// finishTimeout limits swapping the alias and pruning old generations
+const finishTimeout = 2 * time.Minute
+
 // Importer struct represents needed values to import data to elasticsearch
 type (
 	Importer struct {
 		handler   *handler.Handler
 		parser    *parser.Parser
 		config    *config.Ariadna
-		e         *elastic.Client
-		eg        errgroup.Group
-		logger    *logrus.Logger
+		sink      store.Sink
+		store     store.Store
+		ctx       context.Context
+		eg        *errgroup.Group
+		errs      ImportError
+		failed    bool
+		logger    logrus.FieldLogger
 		countries []country
+		postcodes []postcode
+		quarters  []quarter
+		taxonomy  taxonomy
+		stats     *stats
+		// runID tags log lines and the report of the import run
+		runID string
+		// osmTimestamp is the replication timestamp of the extract
+		osmTimestamp *time.Time
+		// stopProgress stops progress logging started by Start
+		stopProgress context.CancelFunc
+		// boundariesCache holds the boundaries file served by the API
+		boundariesCache boundariesCache
 	}
 	country struct {
 		name  string
@@ -43,56 +69,247 @@ type (
 		name string
 		geom *geo.Polygon
 	}
+	postcode struct {
+		code string
+		geom *geo.Polygon
+	}
+	quarter struct {
+		name string
+		geom *geo.Polygon
+	}
 )
 
-// NewImporter creates new instance of importer
-func NewImporter(c *config.Ariadna) (*Importer, error) {
-	i := &Importer{config: c, logger: logrus.New()}
-	if err := i.download(); err != nil {
-		return nil, err
-	}
-	p, err := parser.NewParser(c.OSMFilename)
+// NewImporter creates new instance of importer writing to s. The OSM file
+// is read by Start or Update, so the importer can serve the API as well
+// if s is a store.Store.
+func NewImporter(c *config.Ariadna, s store.Sink, logger logrus.FieldLogger) (*Importer, error) {
+	i := &Importer{config: c, sink: s, logger: logger, stats: newStats()}
+	i.store, _ = s.(store.Store)
+	t, err := newTaxonomy(c.Categories)
 	if err != nil {
 		return nil, err
 	}
-	i.parser = p
-	e, err := elastic.New(c)
+	i.taxonomy = t
+	i.handler = handler.New(c.TagFilters)
+	return i, nil
+}
+
+// load reads the OSM file and builds the polygons used to fill in
+// countries, cities, postcodes and microdistricts
+func (i *Importer) load(ctx context.Context) error {
+	p, err := parser.NewParser(i.config.OSMFilename, i.logger)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	i.e = e
-	i.handler = handler.New()
+	i.parser = p
+	i.stats.setParser(p)
 	i.logger.Info("parser initialized")
-	return i, nil
+	if ts, err := p.Timestamp(); err != nil {
+		i.logger.Warnf("unknown data timestamp: %v", err)
+	} else if !ts.IsZero() {
+		i.osmTimestamp = &ts
+		i.logger.Infof("data timestamp %s", ts.Format(time.RFC3339))
+	}
+	if err := i.parse(ctx); err != nil {
+		return err
+	}
+	i.areasToPolygons()
+	i.postcodesToPolygons()
+	i.quartersToPolygons()
+	return nil
 }
-func (i *Importer) parse() error {
-	return i.parser.Parse(i.handler)
+func (i *Importer) parse(ctx context.Context) error {
+	if !i.config.TwoPass {
+		if err := i.parser.Parse(ctx, i.handler); err != nil {
+			return err
+		}
+		i.stats.parsed(i.parser.Progress())
+		return nil
+	}
+	i.logger.Info("reading ways and relations")
+	if err := i.parser.Parse(ctx, i.handler.WaysPass()); err != nil {
+		return err
+	}
+	i.logger.Info("reading referenced nodes")
+	if err := i.parser.Parse(ctx, i.handler.NodesPass()); err != nil {
+		return err
+	}
+	i.stats.parsed(i.parser.Progress())
+	i.logger.Infof("kept %d nodes and %d relation member ways", i.handler.Nodes.Len(), i.handler.FullWays.Len())
+	return nil
 }
-func (i *Importer) updateIndices() error {
-	return i.e.UpdateIndex()
+func (i *Importer) updateIndices(ctx context.Context) error {
+	now := time.Now()
+	return i.sink.CreateIndex(ctx, store.Meta{Imported: &now, OSMTimestamp: i.osmTimestamp})
 }
 
-// Start starts parsing
-func (i *Importer) Start() error {
-	if err := i.parse(); err != nil {
+// Start downloads the OSM file and starts the import into a new index.
+// Canceling ctx stops the import, the first failed writer cancels the others.
+// Progress is logged every config.ProgressInterval until Report is called.
+func (i *Importer) Start(ctx context.Context) error {
+	ctx = i.begin(ctx)
+	if interval := i.config.ProgressInterval; interval > 0 {
+		progressCtx, cancel := context.WithCancel(ctx)
+		i.stopProgress = cancel
+		go i.logProgress(progressCtx, interval)
+	}
+	if err := i.stage(stageDownload, func() error { return i.download(ctx) }); err != nil {
 		return err
 	}
-	if err := i.updateIndices(); err != nil {
+	if err := i.stage(stageParse, func() error { return i.load(ctx) }); err != nil {
 		return err
 	}
-	i.areasToPolygons()
-	i.eg.Go(i.crossRoadsToElastic)
-	i.eg.Go(i.nodesToElastic)
-	i.eg.Go(i.waysToElastic)
+	i.stats.expect(i.handler.FilteredNodes.Len() + i.handler.Ways.Len())
+	if err := i.stage(stageCreateIndex, func() error { return i.updateIndices(ctx) }); err != nil {
+		return err
+	}
+	eg, egCtx := errgroup.WithContext(ctx)
+	i.eg = eg
+	i.run(egCtx, stageCrossroads, i.crossRoadsToElastic)
+	i.run(egCtx, stageNodes, i.nodesToElastic)
+	i.run(egCtx, stageWays, i.waysToElastic)
 	return nil
 }
 
-// WaitStop is wrapper around waitgroup
-func (i *Importer) WaitStop() {
+// begin tags log lines of the import run with a new id. The returned
+// context carries the tagged logger to the parser and the store.
+func (i *Importer) begin(ctx context.Context) context.Context {
+	i.runID = logging.NewID()
+	i.logger = i.logger.WithField(logging.RunField, i.runID)
+	i.ctx = logging.NewContext(ctx, i.logger)
+	return i.ctx
+}
+
+// run starts the stage in the errgroup and records its error. Errors of
+// stages canceled because another one failed first are not recorded.
+func (i *Importer) run(ctx context.Context, stage string, fn func(context.Context) error) {
+	i.eg.Go(func() error {
+		end := i.stats.begin(stage)
+		err := fn(ctx)
+		end(err)
+		if err != nil && (ctx.Err() == nil || i.ctx.Err() != nil) {
+			i.errs.add(stage, err)
+		}
+		return err
+	})
+}
+
+// WaitStop waits for all stages and returns an *ImportError listing the
+// failed ones. On failure the partially filled index is deleted.
+func (i *Importer) WaitStop() error {
 	i.eg.Wait()
+	if err := i.errs.err(); err != nil {
+		i.failed = true
+		i.cleanup()
+		return err
+	}
+	return nil
 }
+
+// Done points the alias to the new index and deletes previous ones except
+// the last config.KeepIndices. Nothing is touched if the import failed and
+// the new index is deleted if the alias could not be moved to it. The
+// index is complete at this point, so a late interrupt does not stop Done.
 func (i *Importer) Done() error {
-	return i.e.DeleteIndices()
+	if i.failed {
+		return errors.New("import failed, previous index is kept")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), finishTimeout)
+	defer cancel()
+	ctx = logging.NewContext(ctx, i.logger)
+	if err := i.stage(stageSwapAlias, func() error { return i.sink.SwapAlias(ctx) }); err != nil {
+		i.failed = true
+		if !i.served(ctx) {
+			i.cleanup()
+		}
+		return err
+	}
+	// boundaries match the served generation, an export leaves them as is
+	if path := i.config.BoundariesFile; path != "" && i.store != nil {
+		if err := i.writeBoundaries(path); err != nil {
+			i.logger.Warnf("could not write boundaries: %v", err)
+		} else {
+			i.logger.Infof("boundaries written to %s", path)
+		}
+	}
+	var deleted []string
+	err := i.stage(stageDeleteOld, func() (err error) {
+		deleted, err = i.sink.Prune(ctx, i.config.KeepIndices)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	for _, name := range deleted {
+		i.logger.Infof("deleted index %s", name)
+	}
+	return nil
+}
+
+// stage runs fn as the named stage recording its timing for the report
+func (i *Importer) stage(name string, fn func() error) error {
+	end := i.stats.begin(name)
+	err := fn()
+	end(err)
+	if err != nil {
+		return &StageError{Stage: name, Err: err}
+	}
+	return nil
+}
+
+// logProgress logs the progress every interval until ctx is canceled
+func (i *Importer) logProgress(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			i.logger.Infof("progress: %s", i.stats.progress())
+		}
+	}
+}
+
+// Report stops progress logging, updates import metrics and returns the
+// report of the import, err is the error the import failed with
+func (i *Importer) Report(err error) *Report {
+	if i.stopProgress != nil {
+		i.stopProgress()
+	}
+	metrics.ImportDone(err)
+	r := i.stats.report(i.sink.CreatedIndex(), err)
+	r.Run = i.runID
+	return r
+}
+
+// served reports whether the alias points to the created index, a swap
+// request may fail after the cluster applied it
+func (i *Importer) served(ctx context.Context) bool {
+	if i.store == nil {
+		return false
+	}
+	indices, err := i.store.Indices(ctx)
+	if err != nil {
+		return false
+	}
+	for _, index := range indices {
+		if index.Name == i.sink.CreatedIndex() {
+			return index.Aliased
+		}
+	}
+	return false
+}
+
+// cleanup deletes the index being built. The import context may be
+// canceled at this point, so a fresh one is used.
+func (i *Importer) cleanup() {
+	ctx, cancel := context.WithTimeout(context.Background(), cleanupTimeout)
+	defer cancel()
+	ctx = logging.NewContext(ctx, i.logger)
+	if err := i.sink.DeleteCreatedIndex(ctx); err != nil {
+		i.logger.Errorf("could not delete partially built index: %v", err)
+	}
 }
 func uniqString(list []string) []string {
 	uniqueSet := make(map[string]bool)
@@ -112,15 +329,6 @@ func (i *Importer) areasToPolygons() {
 			continue
 		}
 		countryPolygon := i.relationToPolygon(cn)
-
-		f, err := os.Create(cn.Tags["name"])
-		if err != nil {
-			log.Fatal(err)
-		}
-		for _, point := range countryPolygon.Points() {
-			f.Write([]byte(fmt.Sprintf("%v,%v\n", point.Lng(), point.Lat())))
-		}
-		f.Close()
 		c := country{
 			name: cn.Tags["name"],
 			geom: countryPolygon,
@@ -147,40 +355,96 @@ func (i *Importer) areasToPolygons() {
 		i.countries = append(i.countries, c)
 
 	}
+	if len(i.countries) == 0 {
+		var names []string
+		for _, cn := range i.handler.Countries {
+			names = append(names, fmt.Sprintf("%q", cn.Tags["name"]))
+		}
+		sort.Strings(names)
+		if len(names) == 0 {
+			names = []string{"none"}
+		}
+		i.logger.Warnf(
+			"import_country %q matches no admin_level=2 relation in %s, documents will have no country, city and district; countries found: %s",
+			i.config.ImportCountry, i.config.OSMFilename, strings.Join(names, ", "),
+		)
+	}
 	i.logger.Info("finished to build country index")
 }
+func (i *Importer) postcodesToPolygons() {
+	i.logger.Info("started to build postcode index")
+	for _, pc := range i.handler.PostalCodes {
+		code := pc.Tags["postal_code"]
+		if code == "" {
+			code = pc.Tags["ref"]
+		}
+		if code == "" {
+			continue
+		}
+		i.postcodes = append(i.postcodes, postcode{code: code, geom: i.relationToPolygon(pc)})
+	}
+	i.logger.Infof("finished to build postcode index: %d postcodes", len(i.postcodes))
+}
+
+// postcodeAt returns the code of the postal boundary containing the point
+func (i *Importer) postcodeAt(point *geo.Point) string {
+	for _, pc := range i.postcodes {
+		if pc.geom.Contains(point) {
+			return pc.code
+		}
+	}
+	return ""
+}
+func (i *Importer) quartersToPolygons() {
+	i.logger.Info("started to build microdistrict index")
+	for _, q := range i.handler.Quarters {
+		if name := model.TrimQuarterPrefix(q.Tags["name"]); name != "" {
+			i.quarters = append(i.quarters, quarter{name: name, geom: i.wayToPolygon(q)})
+		}
+	}
+	for _, q := range i.handler.QuarterAreas {
+		if name := model.TrimQuarterPrefix(q.Tags["name"]); name != "" {
+			i.quarters = append(i.quarters, quarter{name: name, geom: i.relationToPolygon(q)})
+		}
+	}
+	i.logger.Infof("finished to build microdistrict index: %d microdistricts", len(i.quarters))
+}
+
+// quarterAt returns the name of the microdistrict containing the point
+func (i *Importer) quarterAt(point *geo.Point) string {
+	for _, q := range i.quarters {
+		if q.geom.Contains(point) {
+			return q.name
+		}
+	}
+	return ""
+}
+
+// relationToPolygon joins node and way members of the relation, members are
+// looked up by their type since nodes and ways may share ids
 func (i *Importer) relationToPolygon(area gosmparse.Relation) *geo.Polygon {
 	var points []*geo.Point
 	for _, member := range area.Members {
-		node, ok := i.handler.Nodes[member.ID]
-		if ok {
-			points = append(points, geo.NewPoint(node.Lat, node.Lon))
-		}
-		if !ok {
-			way := i.handler.FullWays[member.ID]
+		switch member.Type {
+		case gosmparse.NodeType:
+			if node, ok := i.handler.Nodes.Get(member.ID); ok {
+				points = append(points, geo.NewPoint(node.Lat, node.Lon))
+			}
+		case gosmparse.WayType:
+			way, _ := i.handler.FullWays.Get(member.ID)
 			for _, nodeID := range way.NodeIDs {
-				node := i.handler.Nodes[nodeID]
+				node, _ := i.handler.Nodes.Get(nodeID)
 				points = append(points, geo.NewPoint(node.Lat, node.Lon))
 			}
 		}
-
 	}
 	return geo.NewPolygon(points)
 }
 func (i *Importer) wayToPolygon(way gosmparse.Way) *geo.Polygon {
 	var points []*geo.Point
 	for _, nodeID := range way.NodeIDs {
-		node := i.handler.Nodes[nodeID]
+		node, _ := i.handler.Nodes.Get(nodeID)
 		points = append(points, geo.NewPoint(node.Lat, node.Lon))
 	}
 	return geo.NewPolygon(points)
 }
-
-func (i *Importer) StartWebServer() error {
-	router := httprouter.New()
-	router.GET("/api/search/:query", i.geoCodeHandler)
-	router.GET("/api/reverse/:lat/:lon", i.reverseGeoCodeHandler)
-	router.NotFound = http.FileServer(http.Dir("public"))
-	http.ListenAndServe(":8080", router)
-	return nil
-}
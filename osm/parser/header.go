@@ -0,0 +1,81 @@
+package parser
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/missinglink/gosmparse/OSMPBF"
+)
+
+// maxHeaderSize limits the size of the header block read by Timestamp,
+// the format caps blob headers at 64 KiB and blobs at 32 MiB
+const maxHeaderSize = 32 << 20
+
+// Timestamp returns the replication timestamp of the extract written by
+// osmosis or osmium, it is zero if the header does not have it
+func (p *Parser) Timestamp() (time.Time, error) {
+	header, err := readHeader(io.NewSectionReader(p.file, 0, p.size))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("reading header: %v", err)
+	}
+	ts := header.GetOsmosisReplicationTimestamp()
+	if ts == 0 {
+		return time.Time{}, nil
+	}
+	return time.Unix(ts, 0).UTC(), nil
+}
+
+// readHeader decodes the first block of the file, which is the header
+func readHeader(r io.Reader) (*OSMPBF.HeaderBlock, error) {
+	var size uint32
+	if err := binary.Read(r, binary.BigEndian, &size); err != nil {
+		return nil, err
+	}
+	if size > maxHeaderSize {
+		return nil, fmt.Errorf("blob header of %d bytes is too large", size)
+	}
+	buf := make([]byte, size)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil, err
+	}
+	var blobHeader OSMPBF.BlobHeader
+	if err := proto.Unmarshal(buf, &blobHeader); err != nil {
+		return nil, err
+	}
+	if blobHeader.GetType() != "OSMHeader" {
+		return nil, fmt.Errorf("first block is %q, not OSMHeader", blobHeader.GetType())
+	}
+	if blobHeader.GetDatasize() > maxHeaderSize {
+		return nil, fmt.Errorf("header of %d bytes is too large", blobHeader.GetDatasize())
+	}
+	buf = make([]byte, blobHeader.GetDatasize())
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil, err
+	}
+	var blob OSMPBF.Blob
+	if err := proto.Unmarshal(buf, &blob); err != nil {
+		return nil, err
+	}
+	data := blob.Raw
+	if blob.ZlibData != nil {
+		zr, err := zlib.NewReader(bytes.NewReader(blob.ZlibData))
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		if data, err = ioutil.ReadAll(io.LimitReader(zr, maxHeaderSize)); err != nil {
+			return nil, err
+		}
+	}
+	var header OSMPBF.HeaderBlock
+	if err := proto.Unmarshal(data, &header); err != nil {
+		return nil, err
+	}
+	return &header, nil
+}
@@ -0,0 +1,40 @@
+package parser
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/missinglink/gosmparse/OSMPBF"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadHeader(t *testing.T) {
+	data, err := proto.Marshal(&OSMPBF.HeaderBlock{
+		RequiredFeatures:            []string{"OsmSchema-V0.6", "DenseNodes"},
+		OsmosisReplicationTimestamp: proto.Int64(1700000000),
+	})
+	require.NoError(t, err)
+	var zdata bytes.Buffer
+	zw := zlib.NewWriter(&zdata)
+	zw.Write(data)
+	zw.Close()
+	blob, err := proto.Marshal(&OSMPBF.Blob{RawSize: proto.Int32(int32(len(data))), ZlibData: zdata.Bytes()})
+	require.NoError(t, err)
+	blobHeader, err := proto.Marshal(&OSMPBF.BlobHeader{Type: proto.String("OSMHeader"), Datasize: proto.Int32(int32(len(blob)))})
+	require.NoError(t, err)
+
+	var file bytes.Buffer
+	binary.Write(&file, binary.BigEndian, uint32(len(blobHeader)))
+	file.Write(blobHeader)
+	file.Write(blob)
+	header, err := readHeader(&file)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1700000000), header.GetOsmosisReplicationTimestamp())
+
+	_, err = readHeader(bytes.NewReader([]byte{0, 0}))
+	assert.Error(t, err)
+}
@@ -1,8 +1,14 @@
 package parser
 
 import (
+	"context"
+	"io"
 	"os"
+	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/maddevsio/ariadna/logging"
 	"github.com/missinglink/gosmparse"
 	"github.com/sirupsen/logrus"
 )
@@ -10,8 +16,60 @@ import (
 // Parser - PBF Parser
 type Parser struct {
 	file    *os.File
+	size    int64
 	decoder *gosmparse.Decoder
-	logger  *logrus.Logger
+	logger  logrus.FieldLogger
+
+	mu      sync.Mutex
+	counter *counter
+	started time.Time
+}
+
+// Progress of a Parse call
+type Progress struct {
+	Nodes     uint64 `json:"nodes"`
+	Ways      uint64 `json:"ways"`
+	Relations uint64 `json:"relations"`
+	// Read is the number of bytes of the file read so far
+	Read    int64     `json:"-"`
+	Size    int64     `json:"-"`
+	Started time.Time `json:"-"`
+}
+
+// Elements returns the number of parsed elements
+func (p Progress) Elements() uint64 {
+	return p.Nodes + p.Ways + p.Relations
+}
+
+// counter counts elements passed to the wrapped reader and stops passing
+// them once stopped is set. The decoder calls it from several goroutines.
+type counter struct {
+	nodes     uint64
+	ways      uint64
+	relations uint64
+	stopped   int32
+	reader    gosmparse.OSMReader
+}
+
+func (c *counter) ReadNode(item gosmparse.Node) {
+	if atomic.LoadInt32(&c.stopped) == 0 {
+		atomic.AddUint64(&c.nodes, 1)
+		c.reader.ReadNode(item)
+	}
+}
+
+func (c *counter) ReadWay(item gosmparse.Way) {
+	if atomic.LoadInt32(&c.stopped) == 0 {
+		atomic.AddUint64(&c.ways, 1)
+		c.reader.ReadWay(item)
+	}
+}
+
+func (c *counter) ReadRelation(item gosmparse.Relation) {
+	if atomic.LoadInt32(&c.stopped) == 0 {
+		atomic.AddUint64(&c.relations, 1)
+		c.reader.ReadRelation(item)
+	}
 }
 
 // open - open file path
@@ -20,25 +78,75 @@ func (p *Parser) open(path string) error {
 	if err != nil {
 		return err
 	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
 	p.file = file
-	p.decoder = gosmparse.NewDecoder(file)
+	p.size = info.Size()
 	return nil
 }
 
-// Parse - execute parser
-func (p *Parser) Parse(handler gosmparse.OSMReader) error {
-	p.logger.Info("parsing started")
-	err := p.decoder.Parse(handler, false)
-	if err != nil {
+// Parse - execute parser. Every call reads the file from the beginning.
+// When ctx is canceled elements are no longer passed to the handler, the
+// decoder then drains the rest of the file quickly and Parse returns
+// ctx.Err() once it is done, so the file is not read after Parse returns.
+func (p *Parser) Parse(ctx context.Context, handler gosmparse.OSMReader) error {
+	if _, err := p.file.Seek(0, io.SeekStart); err != nil {
 		return err
 	}
-	p.logger.Info("parsing finished")
+	c := &counter{reader: handler}
+	d := gosmparse.NewDecoder(p.file)
+	started := time.Now()
+	p.mu.Lock()
+	p.decoder, p.counter, p.started = d, c, started
+	p.mu.Unlock()
+	log := logging.FromContext(ctx, p.logger)
+	log.Info("parsing started")
+	done := make(chan error, 1)
+	go func() {
+		done <- d.Parse(c, false)
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			return err
+		}
+	case <-ctx.Done():
+		atomic.StoreInt32(&c.stopped, 1)
+		<-done
+		log.Info("parsing canceled")
+		return ctx.Err()
+	}
+	elapsed := time.Since(started)
+	total := c.nodes + c.ways + c.relations
+	log.Infof(
+		"parsing finished: %d nodes, %d ways, %d relations in %s (%.0f elements/sec)",
+		c.nodes, c.ways, c.relations, elapsed.Round(time.Millisecond), float64(total)/elapsed.Seconds(),
+	)
 	return nil
 }
 
-// NewParser - Create a new parser for file at path
-func NewParser(path string) (*Parser, error) {
-	p := &Parser{logger: logrus.New()}
+// Progress returns the progress of the running or the last Parse call
+func (p *Parser) Progress() Progress {
+	p.mu.Lock()
+	c, d, started := p.counter, p.decoder, p.started
+	p.mu.Unlock()
+	progress := Progress{Size: p.size, Started: started}
+	if c != nil {
+		progress.Nodes = atomic.LoadUint64(&c.nodes)
+		progress.Ways = atomic.LoadUint64(&c.ways)
+		progress.Relations = atomic.LoadUint64(&c.relations)
+		progress.Read = int64(atomic.LoadUint64(&d.BytesRead))
+	}
+	return progress
+}
+
+// NewParser - Create a new parser for file at path. Parse logs to the
+// logger carried by its context or to logger.
+func NewParser(path string, logger logrus.FieldLogger) (*Parser, error) {
+	p := &Parser{logger: logger}
 	err := p.open(path)
 	if err != nil {
 		return nil, err
@@ -0,0 +1,268 @@
+package osm
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/maddevsio/ariadna/metrics"
+	"github.com/maddevsio/ariadna/osm/parser"
+)
+
+// Report summarizes an import, it can be stored as a build artifact
+type Report struct {
+	// Run is the id tagging log lines of the import
+	Run string `json:"run_id"`
+	// Index is the created index generation or the export file
+	Index    string    `json:"index"`
+	Success  bool      `json:"success"`
+	Started  time.Time `json:"started"`
+	Finished time.Time `json:"finished"`
+	Seconds  float64   `json:"seconds"`
+	// Downloaded is the size of the downloaded extract in bytes
+	Downloaded int64 `json:"downloaded_bytes"`
+	// Elements are counts of parsed OSM elements
+	Elements parser.Progress `json:"elements"`
+	// Generated and Indexed count documents per layer
+	Generated map[string]uint64 `json:"generated"`
+	Indexed   map[string]uint64 `json:"indexed"`
+	Stages    []StageReport     `json:"stages"`
+	Errors    []string          `json:"errors,omitempty"`
+}
+
+// StageReport is the timing of an import stage
+type StageReport struct {
+	Name    string    `json:"name"`
+	Started time.Time `json:"started"`
+	Seconds float64   `json:"seconds"`
+	Error   string    `json:"error,omitempty"`
+}
+
+// WriteFile writes the report to path as JSON
+func (r *Report) WriteFile(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// stats tracks the import for progress logging and the report, stages
+// running concurrently update it
+type stats struct {
+	// downloaded is updated atomically
+	downloaded int64
+
+	mu           sync.Mutex
+	started      time.Time
+	stages       []StageReport
+	phase        string
+	phaseStarted time.Time
+	parser       *parser.Parser
+	elements     parser.Progress
+	downloadSize int64
+	expected     int
+	generated    map[string]uint64
+	indexed      map[string]uint64
+}
+
+func newStats() *stats {
+	return &stats{
+		started:   time.Now(),
+		generated: make(map[string]uint64),
+		indexed:   make(map[string]uint64),
+	}
+}
+
+// begin records the start of the stage and makes it the current phase.
+// The returned func records the end of the stage.
+func (s *stats) begin(name string) func(error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := len(s.stages)
+	now := time.Now()
+	s.stages = append(s.stages, StageReport{Name: name, Started: now})
+	s.phase, s.phaseStarted = name, now
+	return func(err error) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.stages[n].Seconds = time.Since(s.stages[n].Started).Seconds()
+		metrics.StageDuration.WithLabelValues(name).Set(s.stages[n].Seconds)
+		if err != nil {
+			s.stages[n].Error = err.Error()
+		}
+	}
+}
+
+func (s *stats) setParser(p *parser.Parser) {
+	s.mu.Lock()
+	s.parser = p
+	s.mu.Unlock()
+}
+
+// parsed records element counts of the finished pass
+func (s *stats) parsed(p parser.Progress) {
+	s.mu.Lock()
+	s.elements = p
+	s.mu.Unlock()
+}
+
+func (s *stats) setDownloadSize(size int64) {
+	s.mu.Lock()
+	s.downloadSize = size
+	s.mu.Unlock()
+}
+
+// Write counts downloaded bytes
+func (s *stats) Write(b []byte) (int, error) {
+	atomic.AddInt64(&s.downloaded, int64(len(b)))
+	return len(b), nil
+}
+
+// expect adds n to the number of documents the import is expected to
+// generate. Crossroads are added once they are clustered, before any of
+// them is generated.
+func (s *stats) expect(n int) {
+	s.mu.Lock()
+	s.expected += n
+	s.mu.Unlock()
+}
+
+func (s *stats) generate(layer string) {
+	s.mu.Lock()
+	s.generated[layer]++
+	s.mu.Unlock()
+}
+
+func (s *stats) index(counts map[string]uint64) {
+	s.mu.Lock()
+	for layer, n := range counts {
+		s.indexed[layer] += n
+		metrics.DocumentsIndexed.WithLabelValues(layer).Add(float64(n))
+	}
+	s.mu.Unlock()
+}
+
+// progress describes the current phase for the log
+func (s *stats) progress() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	elapsed := time.Since(s.phaseStarted)
+	switch s.phase {
+	case "":
+		return "starting"
+	case stageDownload:
+		downloaded := atomic.LoadInt64(&s.downloaded)
+		if s.downloadSize <= 0 {
+			return fmt.Sprintf("download: %.1f MiB", mib(downloaded))
+		}
+		return fmt.Sprintf(
+			"download: %.1f of %.1f MiB (%.0f%%), eta %s",
+			mib(downloaded), mib(s.downloadSize), percent(float64(downloaded), float64(s.downloadSize)),
+			eta(float64(downloaded), float64(s.downloadSize), elapsed),
+		)
+	case stageParse:
+		if s.parser == nil {
+			return "parse: opening the file"
+		}
+		p := s.parser.Progress()
+		return fmt.Sprintf(
+			"parse: %d elements, %.0f%% of the file, eta %s",
+			p.Elements(), percent(float64(p.Read), float64(p.Size)),
+			eta(float64(p.Read), float64(p.Size), time.Since(p.Started)),
+		)
+	case stageCrossroads, stageNodes, stageWays:
+		generated := sum(s.generated)
+		return fmt.Sprintf(
+			"documents: generated %d of about %d (%s), indexed %d (%s), eta %s",
+			generated, s.expected, layers(s.generated), sum(s.indexed), layers(s.indexed),
+			eta(float64(generated), float64(s.expected), elapsed),
+		)
+	}
+	return fmt.Sprintf("%s: running for %s", s.phase, elapsed.Round(time.Second))
+}
+
+// report returns the report of the import failed with err or nil
+func (s *stats) report(index string, err error) *Report {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	finished := time.Now()
+	r := &Report{
+		Index:      index,
+		Success:    err == nil,
+		Started:    s.started,
+		Finished:   finished,
+		Seconds:    finished.Sub(s.started).Seconds(),
+		Downloaded: atomic.LoadInt64(&s.downloaded),
+		Elements:   s.elements,
+		Generated:  make(map[string]uint64, len(s.generated)),
+		Indexed:    make(map[string]uint64, len(s.indexed)),
+		Stages:     append([]StageReport(nil), s.stages...),
+	}
+	for layer, n := range s.generated {
+		r.Generated[layer] = n
+	}
+	for layer, n := range s.indexed {
+		r.Indexed[layer] = n
+	}
+	if importErr, ok := err.(*ImportError); ok {
+		for _, e := range importErr.Errors {
+			r.Errors = append(r.Errors, e.Error())
+		}
+	} else if err != nil {
+		r.Errors = []string{err.Error()}
+	}
+	return r
+}
+
+func mib(n int64) float64 {
+	return float64(n) / (1 << 20)
+}
+
+func percent(done, total float64) float64 {
+	if total <= 0 {
+		return 0
+	}
+	return 100 * done / total
+}
+
+// eta estimates the time left assuming the rate stays the same
+func eta(done, total float64, elapsed time.Duration) string {
+	if done <= 0 || total <= 0 {
+		return "unknown"
+	}
+	if done >= total {
+		return "0s"
+	}
+	return time.Duration(float64(elapsed) * (total - done) / done).Round(time.Second).String()
+}
+
+func sum(counts map[string]uint64) uint64 {
+	var n uint64
+	for _, c := range counts {
+		n += c
+	}
+	return n
+}
+
+// layers formats counts as "address 10, poi 2" sorted by layer
+func layers(counts map[string]uint64) string {
+	if len(counts) == 0 {
+		return "none"
+	}
+	names := make([]string, 0, len(counts))
+	for layer := range counts {
+		names = append(names, layer)
+	}
+	sort.Strings(names)
+	parts := make([]string, len(names))
+	for i, layer := range names {
+		parts[i] = fmt.Sprintf("%s %d", layer, counts[layer])
+	}
+	return strings.Join(parts, ", ")
+}
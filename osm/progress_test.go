@@ -0,0 +1,48 @@
+package osm
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/maddevsio/ariadna/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReport(t *testing.T) {
+	s := newStats()
+	s.begin(stageDownload)(nil)
+	s.Write(make([]byte, 1024))
+	end := s.begin(stageNodes)
+	s.expect(3)
+	s.expect(1)
+	s.generate(model.LayerAddress)
+	s.generate(model.LayerAddress)
+	s.generate(model.LayerPOI)
+	assert.Contains(t, s.progress(), "generated 3 of about 4 (address 2, poi 1), indexed 0 (none)")
+	s.index(map[string]uint64{model.LayerAddress: 2})
+	end(errors.New("bulk insert failed"))
+
+	var importErr ImportError
+	importErr.add(stageNodes, errors.New("bulk insert failed"))
+	r := s.report("addresses-1", &importErr)
+	assert.Equal(t, "addresses-1", r.Index)
+	assert.False(t, r.Success)
+	assert.Equal(t, int64(1024), r.Downloaded)
+	assert.Equal(t, map[string]uint64{model.LayerAddress: 2, model.LayerPOI: 1}, r.Generated)
+	assert.Equal(t, map[string]uint64{model.LayerAddress: 2}, r.Indexed)
+	assert.Equal(t, []string{"nodes: bulk insert failed"}, r.Errors)
+	require.Len(t, r.Stages, 2)
+	assert.Equal(t, stageDownload, r.Stages[0].Name)
+	assert.Equal(t, "bulk insert failed", r.Stages[1].Error)
+
+	assert.True(t, s.report("addresses-1", nil).Success)
+}
+
+func TestETA(t *testing.T) {
+	assert.Equal(t, "30s", eta(25, 100, 10*time.Second))
+	assert.Equal(t, "unknown", eta(0, 100, time.Second))
+	assert.Equal(t, "unknown", eta(10, -1, time.Second))
+	assert.Equal(t, "0s", eta(100, 100, time.Second))
+}
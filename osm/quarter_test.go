@@ -0,0 +1,59 @@
+package osm
+
+import (
+	"testing"
+
+	geo "github.com/kellydunn/golang-geo"
+	"github.com/maddevsio/ariadna/config"
+	"github.com/maddevsio/ariadna/model"
+	"github.com/maddevsio/ariadna/osm/handler"
+	"github.com/missinglink/gosmparse"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+// readSquare reads four nodes starting at id and a closed way of them
+func readSquare(h *handler.Handler, id int64, lat, lon, size float64, tags map[string]string) {
+	h.ReadNode(gosmparse.Node{ID: id, Lat: lat, Lon: lon})
+	h.ReadNode(gosmparse.Node{ID: id + 1, Lat: lat + size, Lon: lon})
+	h.ReadNode(gosmparse.Node{ID: id + 2, Lat: lat + size, Lon: lon + size})
+	h.ReadNode(gosmparse.Node{ID: id + 3, Lat: lat, Lon: lon + size})
+	h.ReadWay(gosmparse.Way{ID: id, NodeIDs: []int64{id, id + 1, id + 2, id + 3, id}, Tags: tags})
+}
+
+func TestQuarterAt(t *testing.T) {
+	h := handler.New(config.DefaultTagFilters)
+	// a microdistrict mapped as a way
+	readSquare(h, 1, 42.80, 74.50, 0.02, map[string]string{"place": "microdistrict", "name": "мкр Джал"})
+	// a microdistrict mapped as a relation of its outline, way 10 shares
+	// its id with node 10
+	readSquare(h, 10, 42.83, 74.58, 0.02, nil)
+	h.ReadRelation(gosmparse.Relation{
+		ID:      100,
+		Members: []gosmparse.RelationMember{{ID: 10, Type: gosmparse.WayType, Role: "outer"}},
+		Tags:    map[string]string{"place": "quarter", "name": "микрорайон Асанбай"},
+	})
+	i := &Importer{config: &config.Ariadna{}, handler: h, logger: logrus.New(), stats: newStats()}
+	i.quartersToPolygons()
+	assert.Len(t, i.quarters, 2)
+
+	inJal := model.Location{Lat: 42.81, Lon: 74.51}
+	cases := []struct {
+		name     string
+		tags     map[string]string
+		location model.Location
+		want     string
+	}{
+		{"way polygon", nil, inJal, "Джал"},
+		{"relation polygon", nil, model.Location{Lat: 42.84, Lon: 74.59}, "Асанбай"},
+		{"outside", nil, model.Location{Lat: 42.9, Lon: 74.7}, ""},
+		{"addr:quarter", map[string]string{"addr:quarter": "ж/м Ак-Ордо"}, inJal, "Ак-Ордо"},
+		{"addr:place", map[string]string{"addr:place": "мкр Тунгуч", "addr:quarter": "Ак-Ордо"}, inJal, "Тунгуч"},
+		{"prefix only", map[string]string{"addr:place": "мкр"}, inJal, "Джал"},
+	}
+	for _, c := range cases {
+		a := i.newAddress(model.NodeType, 1000, c.tags, c.location)
+		assert.Equal(t, c.want, a.Quarter, c.name)
+	}
+	assert.Equal(t, "Асанбай", i.quarterAt(geo.NewPoint(42.84, 74.59)))
+}
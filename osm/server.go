@@ -0,0 +1,111 @@
+package osm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/maddevsio/ariadna/logging"
+	"github.com/maddevsio/ariadna/metrics"
+	"github.com/sirupsen/logrus"
+)
+
+// shutdownTimeout limits waiting for in-flight requests on shutdown
+const shutdownTimeout = 15 * time.Second
+
+// Endpoints as labeled in metrics, the API has no autocomplete endpoint
+const (
+	endpointSearch     = "search"
+	endpointReverse    = "reverse"
+	endpointPlaces     = "places"
+	endpointBoundaries = "boundaries"
+	endpointStatus     = "status"
+)
+
+// statusRecorder remembers the status code written by a handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// instrument counts requests of the endpoint by status code and observes
+// their latency
+func instrument(endpoint string, h httprouter.Handle) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		started := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		h(rec, r, ps)
+		metrics.HTTPDuration.WithLabelValues(endpoint).Observe(time.Since(started).Seconds())
+		metrics.HTTPRequests.WithLabelValues(endpoint, strconv.Itoa(rec.status)).Inc()
+	}
+}
+
+// log returns the logger of the request tagged with its id
+func (i *Importer) log(ctx context.Context) logrus.FieldLogger {
+	return logging.FromContext(ctx, i.logger)
+}
+
+// StartWebServer serves the API until ctx is canceled, then waits for
+// in-flight requests to finish. It returns an error if the server could
+// not start.
+func (i *Importer) StartWebServer(ctx context.Context) error {
+	if i.store == nil {
+		return errors.New("the store does not support serving")
+	}
+	conf := i.config.Server
+	router := httprouter.New()
+	router.GET("/api/search/:query", instrument(endpointSearch, i.geoCodeHandler))
+	router.GET("/api/reverse/:lat/:lon", instrument(endpointReverse, i.reverseGeoCodeHandler))
+	router.GET("/api/places", instrument(endpointPlaces, i.placesHandler))
+	router.GET("/api/boundaries", instrument(endpointBoundaries, i.boundariesHandler))
+	router.GET("/api/status", instrument(endpointStatus, i.statusHandler))
+	router.GET("/health", i.healthHandler)
+	router.GET("/ready", i.readyHandler)
+	router.Handler(http.MethodGet, "/metrics", metrics.Handler())
+	if conf.StaticDir != "" {
+		if _, err := os.Stat(conf.StaticDir); err != nil {
+			return fmt.Errorf("static dir: %v", err)
+		}
+		router.NotFound = http.FileServer(http.Dir(conf.StaticDir))
+	}
+	srv := &http.Server{
+		Addr:         conf.Listen,
+		Handler:      logging.RequestID(i.logger, router),
+		ReadTimeout:  conf.ReadTimeout,
+		WriteTimeout: conf.WriteTimeout,
+		IdleTimeout:  conf.IdleTimeout,
+	}
+	ln, err := net.Listen("tcp", conf.Listen)
+	if err != nil {
+		return err
+	}
+	errs := make(chan error, 1)
+	go func() {
+		if conf.TLS() {
+			errs <- srv.ServeTLS(ln, conf.TLSCert, conf.TLSKey)
+		} else {
+			errs <- srv.Serve(ln)
+		}
+	}()
+	i.logger.Infof("listening on %s", conf.Listen)
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+	i.logger.Info("shutting down web server")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	return srv.Shutdown(shutdownCtx)
+}
@@ -0,0 +1,65 @@
+package osm
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/maddevsio/ariadna/metrics"
+	"github.com/maddevsio/ariadna/store"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInstrument(t *testing.T) {
+	badRequests := metrics.HTTPRequests.WithLabelValues(endpointPlaces, "400")
+	ok := metrics.HTTPRequests.WithLabelValues(endpointPlaces, "200")
+	before := testutil.ToFloat64(badRequests)
+	beforeOK := testutil.ToFloat64(ok)
+
+	i := &Importer{}
+	h := instrument(endpointPlaces, i.placesHandler)
+	w := httptest.NewRecorder()
+	h(w, httptest.NewRequest(http.MethodGet, "/api/places?lat=1", nil), httprouter.Params{})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, before+1, testutil.ToFloat64(badRequests))
+
+	h = instrument(endpointPlaces, func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		w.Write([]byte("[]"))
+	})
+	h(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/places", nil), nil)
+	assert.Equal(t, beforeOK+1, testutil.ToFloat64(ok))
+}
+
+// statusStore is a store.Store reporting a fixed status
+type statusStore struct {
+	store.Store
+	status store.Status
+	err    error
+}
+
+func (s statusStore) Status(ctx context.Context) (store.Status, error) {
+	return s.status, s.err
+}
+
+func TestReady(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		store statusStore
+		code  int
+	}{
+		{"unreachable", statusStore{err: errors.New("connection refused")}, http.StatusServiceUnavailable},
+		{"no alias", statusStore{}, http.StatusServiceUnavailable},
+		{"empty", statusStore{status: store.Status{Index: "addresses-1"}}, http.StatusServiceUnavailable},
+		{"ready", statusStore{status: store.Status{Index: "addresses-1", Documents: 10}}, http.StatusOK},
+	} {
+		i := &Importer{store: tc.store, logger: logrus.New()}
+		w := httptest.NewRecorder()
+		i.readyHandler(w, httptest.NewRequest(http.MethodGet, "/ready", nil), nil)
+		assert.Equal(t, tc.code, w.Code, tc.name)
+	}
+}
@@ -0,0 +1,60 @@
+package osm
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/maddevsio/ariadna/store"
+)
+
+// statusTimeout limits requests to the store made by readiness and status
+// checks, so probes fail instead of hanging on an unreachable cluster
+const statusTimeout = 5 * time.Second
+
+// Probe is the response of health and readiness checks
+type Probe struct {
+	Status string `json:"status"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// healthHandler reports the process is alive, it does not touch the store
+func (i *Importer) healthHandler(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	writeJSON(w, http.StatusOK, Probe{Status: "ok"})
+}
+
+// readyHandler reports whether the API can answer queries: the store is
+// reachable and the alias points to a generation with documents
+func (i *Importer) readyHandler(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	st, err := i.status(r.Context())
+	switch {
+	case err != nil:
+		i.log(r.Context()).Warnf("readiness check failed: %v", err)
+		writeJSON(w, http.StatusServiceUnavailable, Probe{Status: "unavailable", Reason: "store is unreachable"})
+	case st.Index == "":
+		writeJSON(w, http.StatusServiceUnavailable, Probe{Status: "unavailable", Reason: "no index is served, run import first"})
+	case st.Documents == 0:
+		writeJSON(w, http.StatusServiceUnavailable, Probe{Status: "unavailable", Reason: "served index " + st.Index + " is empty"})
+	default:
+		writeJSON(w, http.StatusOK, Probe{Status: "ok"})
+	}
+}
+
+// statusHandler describes the served generation: its name, the number of
+// documents, when it was imported and how fresh the OSM data is
+func (i *Importer) statusHandler(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	st, err := i.status(r.Context())
+	if err != nil {
+		i.log(r.Context()).Errorf("status failed: %v", err)
+		writeJSON(w, http.StatusServiceUnavailable, BadRequest{Error: "store is unreachable"})
+		return
+	}
+	writeJSON(w, http.StatusOK, st)
+}
+
+func (i *Importer) status(ctx context.Context) (store.Status, error) {
+	ctx, cancel := context.WithTimeout(ctx, statusTimeout)
+	defer cancel()
+	return i.store.Status(ctx)
+}
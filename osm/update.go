@@ -0,0 +1,151 @@
+package osm
+
+import (
+	"context"
+	"time"
+
+	"github.com/maddevsio/ariadna/osm/diff"
+	"github.com/maddevsio/ariadna/store"
+	"github.com/missinglink/gosmparse"
+)
+
+// Update applies the OSM change file at diffPath to the index behind the
+// alias. The OSM file is read first to locate ways and to fill in admin
+// names, so it must be the extract the index was built from.
+//
+// Only address nodes and ways are updated: changed relations and
+// crossroads are picked up by the next full import.
+func (i *Importer) Update(ctx context.Context, diffPath string) error {
+	ctx = i.begin(ctx)
+	if err := i.load(ctx); err != nil {
+		return &StageError{Stage: stageParse, Err: err}
+	}
+	change, err := diff.Open(diffPath)
+	if err != nil {
+		return &StageError{Stage: stageDiff, Err: err}
+	}
+	docs := i.applyChange(change)
+	if len(docs) == 0 {
+		i.logger.Info("nothing to update")
+	} else if err := i.sink.BulkUpdate(ctx, docs); err != nil {
+		return &StageError{Stage: stageUpdate, Err: err}
+	}
+	i.updateMeta(ctx, change.Timestamp)
+	return nil
+}
+
+// updateMeta records in the served generation when it was updated and the
+// timestamp of the applied diff. The documents are already written, so a
+// failure is only logged.
+func (i *Importer) updateMeta(ctx context.Context, ts time.Time) {
+	if i.store == nil {
+		return
+	}
+	st, err := i.store.Status(ctx)
+	if err != nil {
+		i.log(ctx).Warnf("could not read index meta: %v", err)
+		return
+	}
+	meta := st.Meta
+	now := time.Now().UTC()
+	meta.Updated = &now
+	if !ts.IsZero() {
+		meta.OSMTimestamp = &ts
+	}
+	if err := i.store.SetMeta(ctx, meta); err != nil {
+		i.log(ctx).Warnf("could not update index meta: %v", err)
+	}
+}
+
+// applyChange stores the changed elements in the handler and returns the
+// documents they affect, deleted documents have no address. Documents are deleted only for
+// elements which were addresses before the change. Ways are reindexed when
+// any of their nodes moved or was deleted.
+func (i *Importer) applyChange(change *diff.Change) []store.Document {
+	var docs []store.Document
+	moved := make(map[int64]bool, len(change.Nodes)+len(change.DeletedNodes))
+	deleted := make(map[int64]bool, len(change.DeletedNodes))
+	for _, id := range change.DeletedNodes {
+		_, indexed := i.handler.FilteredNodes.Get(id)
+		i.handler.Nodes.Delete(id)
+		i.handler.FilteredNodes.Delete(id)
+		moved[id], deleted[id] = true, true
+		if indexed {
+			docs = append(docs, store.Document{ID: docID("n", id)})
+		}
+	}
+	for _, id := range change.DeletedWays {
+		_, indexed := i.handler.Ways.Get(id)
+		i.handler.FullWays.Delete(id)
+		i.handler.Ways.Delete(id)
+		if indexed {
+			docs = append(docs, store.Document{ID: docID("w", id)})
+		}
+	}
+	for _, node := range change.Nodes {
+		_, indexed := i.handler.FilteredNodes.Get(node.ID)
+		i.handler.FilteredNodes.Delete(node.ID)
+		i.handler.ReadNode(node)
+		moved[node.ID] = true
+		if _, ok := i.handler.FilteredNodes.Get(node.ID); !ok {
+			if indexed {
+				docs = append(docs, store.Document{ID: docID("n", node.ID)})
+			}
+			continue
+		}
+		docs = append(docs, store.Document{ID: docID("n", node.ID), Address: i.nodeToAddress(node)})
+	}
+	changed := make(map[int64]bool, len(change.Ways))
+	for _, way := range change.Ways {
+		_, indexed := i.handler.Ways.Get(way.ID)
+		i.handler.Ways.Delete(way.ID)
+		i.handler.ReadWay(way)
+		changed[way.ID] = true
+		if _, ok := i.handler.Ways.Get(way.ID); !ok && indexed {
+			docs = append(docs, store.Document{ID: docID("w", way.ID)})
+		}
+	}
+	var unresolved int
+	i.handler.Ways.Range(func(way gosmparse.Way) bool {
+		if !changed[way.ID] && !hasAny(way.NodeIDs, moved) {
+			return true
+		}
+		// with two_pass only nodes the extract referenced are known, a
+		// way using others would get a wrong centroid
+		if id, ok := i.missingNode(way, deleted); !ok {
+			i.logger.Warnf("way %d is not updated: node %d is unknown", way.ID, id)
+			unresolved++
+			return true
+		}
+		docs = append(docs, store.Document{ID: docID("w", way.ID), Address: i.wayToAddress(way)})
+		return true
+	})
+	i.logger.Infof(
+		"diff: %d nodes and %d ways changed, %d nodes and %d ways deleted",
+		len(change.Nodes), len(change.Ways), len(change.DeletedNodes), len(change.DeletedWays),
+	)
+	if unresolved > 0 {
+		i.logger.Warnf("%d ways skipped, run a full import to update them", unresolved)
+	}
+	return docs
+}
+
+// missingNode returns the first node of the way which is neither known nor
+// deleted and false, or true if there is none
+func (i *Importer) missingNode(way gosmparse.Way, deleted map[int64]bool) (int64, bool) {
+	for _, id := range way.NodeIDs {
+		if _, ok := i.handler.Nodes.Get(id); !ok && !deleted[id] {
+			return id, false
+		}
+	}
+	return 0, true
+}
+
+func hasAny(ids []int64, set map[int64]bool) bool {
+	for _, id := range ids {
+		if set[id] {
+			return true
+		}
+	}
+	return false
+}
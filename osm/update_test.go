@@ -0,0 +1,58 @@
+package osm
+
+import (
+	"testing"
+
+	"github.com/maddevsio/ariadna/config"
+	"github.com/maddevsio/ariadna/model"
+	"github.com/maddevsio/ariadna/osm/diff"
+	"github.com/maddevsio/ariadna/osm/handler"
+	"github.com/missinglink/gosmparse"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyChange(t *testing.T) {
+	h := handler.New(config.DefaultTagFilters)
+	house := map[string]string{"addr:street": "Чуй", "addr:housenumber": "120"}
+	h.ReadNode(gosmparse.Node{ID: 1, Lat: 42.87, Lon: 74.59})
+	h.ReadNode(gosmparse.Node{ID: 2, Lat: 42.88, Lon: 74.60})
+	h.ReadNode(gosmparse.Node{ID: 3, Lat: 42.88, Lon: 74.61, Tags: house})
+	h.ReadWay(gosmparse.Way{ID: 10, NodeIDs: []int64{1, 2}, Tags: house})
+	h.ReadWay(gosmparse.Way{ID: 11, NodeIDs: []int64{2, 1}, Tags: house})
+	i := &Importer{config: &config.Ariadna{}, handler: h, logger: logrus.New(), stats: newStats()}
+
+	h.ReadNode(gosmparse.Node{ID: 4, Lat: 42.89, Lon: 74.62})
+	h.ReadNode(gosmparse.Node{ID: 5, Lat: 42.89, Lon: 74.63})
+	h.ReadWay(gosmparse.Way{ID: 12, NodeIDs: []int64{4, 5}, Tags: house})
+
+	docs := i.applyChange(&diff.Change{
+		// node 1 moved, so way 10 is reindexed too, node 1 was not an
+		// address, so there is no document to delete
+		Nodes: []gosmparse.Node{{ID: 1, Lat: 42.9, Lon: 74.59}},
+		Ways: []gosmparse.Way{
+			{ID: 11, NodeIDs: []int64{2, 1}},
+			// node 99 is not in the extract, as with two_pass
+			{ID: 13, NodeIDs: []int64{2, 99}, Tags: house},
+		},
+		// way 12 lost node 5 and is placed at node 4
+		DeletedNodes: []int64{3, 5},
+	})
+	actions := make(map[string]*model.Address)
+	for _, doc := range docs {
+		actions[doc.ID] = doc.Address
+	}
+	require.Len(t, actions, 4)
+	for _, id := range []string{"n3", "w11"} {
+		address, ok := actions[id]
+		assert.True(t, ok && address == nil, "%s must be deleted", id)
+	}
+	assert.NotNil(t, actions["w10"])
+	require.NotNil(t, actions["w12"])
+	assert.Equal(t, model.Location{Lat: 42.89, Lon: 74.62}, actions["w12"].Location, "way 12 must be placed at its remaining node")
+	_, ok := h.Ways.Get(11)
+	assert.False(t, ok, "way without address tags must be dropped")
+	_, ok = h.FilteredNodes.Get(3)
+	assert.False(t, ok)
+}
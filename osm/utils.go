@@ -1,7 +1,7 @@
 package osm
 
 import (
-	"encoding/json"
+	"strconv"
 	"strings"
 
 	geo "github.com/kellydunn/golang-geo"
@@ -9,35 +9,88 @@ import (
 	"github.com/missinglink/gosmparse"
 )
 
-func (i *Importer) wayToJSON(way gosmparse.Way) ([]byte, error) {
+// wayToAddress places the way at the centroid of its nodes, unknown nodes
+// are left out rather than counted at 0,0
+func (i *Importer) wayToAddress(way gosmparse.Way) *model.Address {
 	var coords [][]float64
 	for _, nodeID := range way.NodeIDs {
-		node := i.handler.Nodes[nodeID]
-		coords = append(coords, []float64{node.Lon, node.Lat})
+		if node, ok := i.handler.Nodes.Get(nodeID); ok {
+			coords = append(coords, []float64{node.Lon, node.Lat})
+		}
 	}
-	x := 0.0
-	y := 0.0
-	numPoints := float64(len(coords))
-	for _, point := range coords {
-		x += point[0]
-		y += point[1]
+	var location model.Location
+	if numPoints := float64(len(coords)); numPoints > 0 {
+		x := 0.0
+		y := 0.0
+		for _, point := range coords {
+			x += point[0]
+			y += point[1]
+		}
+		location = model.Location{Lat: y / numPoints, Lon: x / numPoints}
 	}
-	return i.marshalJSON(way.Tags, model.Location{Lat: y / numPoints, Lon: x / numPoints})
+	return i.newAddress(model.WayType, way.ID, way.Tags, location)
+}
+
+func (i *Importer) nodeToAddress(node gosmparse.Node) *model.Address {
+	return i.newAddress(model.NodeType, node.ID, node.Tags, model.Location{Lat: node.Lat, Lon: node.Lon})
 }
 
-func (i *Importer) nodeToJSON(node gosmparse.Node) ([]byte, error) {
-	return i.marshalJSON(node.Tags, model.Location{Lat: node.Lat, Lon: node.Lon})
+// docID returns the document id for the OSM element, prefixed by its type
+// so that nodes, ways and crossroads sharing a numeric id do not collide
+func docID(prefix string, id int64) string {
+	return prefix + strconv.FormatInt(id, 10)
 }
 
-func (i *Importer) marshalJSON(tags map[string]string, location model.Location) ([]byte, error) {
+// layer classifies the element by its tags. Elements with taxonomy
+// categories are POIs even when they carry an address.
+func layer(tags map[string]string, categories []string) string {
+	switch {
+	case len(categories) > 0:
+		return model.LayerPOI
+	case tags["addr:housenumber"] != "":
+		return model.LayerAddress
+	case tags["highway"] != "":
+		return model.LayerStreet
+	case tags["place"] != "" || tags["boundary"] != "":
+		return model.LayerAdmin
+	}
+	return model.LayerPOI
+}
+
+func (i *Importer) storedTags(tags map[string]string) map[string]string {
+	var stored map[string]string
+	for _, key := range i.config.StoredTags {
+		if val, ok := tags[key]; ok {
+			if stored == nil {
+				stored = make(map[string]string)
+			}
+			stored[key] = val
+		}
+	}
+	return stored
+}
+
+// newAddress returns the document of the element
+func (i *Importer) newAddress(osmType string, id int64, tags map[string]string, location model.Location) *model.Address {
 	var street = tags["addr:street"]
 	var name = tags["name"]
 	var houseNumber = tags["addr:housenumber"]
+	var categories = i.taxonomy.categories(tags)
 	var address = model.Address{
+		OSMType:     osmType,
+		OSMID:       id,
+		Layer:       layer(tags, categories),
+		Categories:  categories,
+		Tags:        i.storedTags(tags),
 		Street:      street,
 		Name:        name,
 		Location:    location,
 		HouseNumber: houseNumber,
+		Postcode:    tags["addr:postcode"],
+		Quarter:     model.TrimQuarterPrefix(tags["addr:place"]),
+	}
+	if address.Quarter == "" {
+		address.Quarter = model.TrimQuarterPrefix(tags["addr:quarter"])
 	}
 	if address.Street != "" {
 		if strings.Contains(address.Street, "улица") {
@@ -57,9 +110,15 @@ func (i *Importer) marshalJSON(tags map[string]string, location model.Location)
 			address.Street = strings.TrimSpace(strings.Replace(address.Street, "переулок", "", -1))
 		}
 	}
+	point := geo.NewPoint(address.Location.Lat, address.Location.Lon)
+	if address.Postcode == "" {
+		address.Postcode = i.postcodeAt(point)
+	}
+	if address.Quarter == "" {
+		address.Quarter = i.quarterAt(point)
+	}
 	for countryID := range i.countries {
 		country := i.countries[countryID]
-		point := geo.NewPoint(address.Location.Lat, address.Location.Lon)
 		if country.geom.Contains(point) {
 			address.Country = country.name
 		}
@@ -87,5 +146,6 @@ func (i *Importer) marshalJSON(tags map[string]string, location model.Location)
 
 	}
 
-	return json.Marshal(address)
+	i.stats.generate(address.Layer)
+	return &address
 }
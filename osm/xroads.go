@@ -1,99 +1,183 @@
 package osm
 
 import (
-	"bytes"
-	"encoding/json"
-	"fmt"
+	"context"
 	"sort"
-	"strconv"
 	"strings"
 
 	geo "github.com/kellydunn/golang-geo"
 	"github.com/maddevsio/ariadna/model"
 )
 
-func (i *Importer) crossRoadsToElastic() error {
+type (
+	// crossroadNode is a single node shared by ways with different names
+	crossroadNode struct {
+		id       int64
+		names    []string
+		location model.Location
+	}
+	// crossroad is a cluster of nearby nodes shared by the same streets
+	crossroad struct {
+		names    []string
+		nodeIDs  []int64
+		location model.Location
+	}
+)
+
+func (i *Importer) crossRoadsToElastic(ctx context.Context) error {
 	i.logger.Info("started to search crossroads")
-	buf, err := i.searchCrossRoads()
+	b, err := i.searchCrossRoads(ctx)
 	if err != nil {
 		return err
 	}
 	i.logger.Info("crossroads found")
-	return i.e.BulkWrite(buf)
+	return i.bulkWrite(ctx, b)
 }
 
-func (i *Importer) searchCrossRoads() (bytes.Buffer, error) {
-	var buf bytes.Buffer
+func (i *Importer) searchCrossRoads(ctx context.Context) (*batch, error) {
+	var b batch
 	replacer := strings.NewReplacer(
 		"улица", "",
 		"переулок", "",
 		"бульвар", "",
 		"проспект", "",
 	)
-	for nodeid, wayids := range i.handler.InvertedIndex {
-		uniqueWayIds := uniqString(wayids)
-		if len(uniqueWayIds) > 1 {
-			var names []string
-			sort.Strings(uniqueWayIds)
-			for _, wayid := range uniqueWayIds {
-				names = append(names, i.handler.WayNames[wayid])
+	nodes := i.crossRoadNodes()
+	crossroads := clusterCrossRoads(nodes, i.config.CrossroadClusterDistance)
+	i.logger.Infof("%d intersection nodes clustered into %d crossroads", len(nodes), len(crossroads))
+	i.stats.expect(len(crossroads))
+	for _, cr := range crossroads {
+		if err := ctx.Err(); err != nil {
+			return &b, err
+		}
+		streets := make([]string, 0, len(cr.names))
+		for _, name := range cr.names {
+			streets = append(streets, strings.TrimSpace(replacer.Replace(name)))
+		}
+		address := model.Address{
+			OSMType:      model.NodeType,
+			OSMID:        cr.nodeIDs[0],
+			Layer:        model.LayerIntersection,
+			Country:      "KG",
+			Name:         replacer.Replace(strings.Join(cr.names, " ")),
+			Location:     cr.location,
+			Intersection: true,
+			Streets:      streets,
+			NodeIDs:      cr.nodeIDs,
+		}
+		for countryID := range i.countries {
+			country := i.countries[countryID]
+			lat := address.Location.Lat
+			lon := address.Location.Lon
+			point := geo.NewPoint(lat, lon)
+			if country.geom.Contains(point) {
+				address.Country = country.name
 			}
-			var uniqueNames = uniqString(names)
-			sort.Strings(uniqueNames)
-			if len(uniqueNames) > 1 {
-				id, err := strconv.Atoi(nodeid)
-				if err != nil {
-					return buf, err
-				}
-				node := i.handler.Nodes[int64(id)]
-				address := model.Address{
-					Country:      "KG",
-					Name:         replacer.Replace(strings.Join(uniqueNames, " ")),
-					Location:     model.Location{Lat: node.Lat, Lon: node.Lon},
-					Intersection: true,
-				}
-				for countryID := range i.countries {
-					country := i.countries[countryID]
-					lat := address.Location.Lat
-					lon := address.Location.Lon
-					point := geo.NewPoint(lat, lon)
-					if country.geom.Contains(point) {
-						address.Country = country.name
+			for townID := range country.towns {
+				town := country.towns[townID]
+				if town.geom.Contains(point) {
+					switch town.placeType {
+					case "city":
+						address.City = town.name
+					case "town":
+						address.Town = town.name
+					case "hamlet":
+						address.Village = town.name
+					case "village":
+						address.Village = town.name
 					}
-					for townID := range country.towns {
-						town := country.towns[townID]
-						if town.geom.Contains(point) {
-							switch town.placeType {
-							case "city":
-								address.City = town.name
-							case "town":
-								address.Town = town.name
-							case "hamlet":
-								address.Village = town.name
-							case "village":
-								address.Village = town.name
-							}
-						}
-						for districtID := range town.districts {
-							district := town.districts[districtID]
-							if district.geom.Contains(point) {
-								address.District = district.name
-							}
-						}
+				}
+				for districtID := range town.districts {
+					district := town.districts[districtID]
+					if district.geom.Contains(point) {
+						address.District = district.name
 					}
 				}
+			}
+		}
+
+		i.stats.generate(address.Layer)
+		b.add(docID("x", cr.nodeIDs[0]), &address)
+	}
+	return &b, nil
+}
 
-				data, err := json.Marshal(address)
-				if err != nil {
-					return buf, err
+// crossRoadNodes returns nodes shared by ways with at least two different names
+func (i *Importer) crossRoadNodes() []crossroadNode {
+	var nodes []crossroadNode
+	i.handler.EachSharedNode(func(nodeID int64, wayIDs []int64) {
+		names := make([]string, 0, len(wayIDs))
+		for _, wayID := range wayIDs {
+			names = append(names, i.handler.WayNames[wayID])
+		}
+		names = uniqString(names)
+		if len(names) < 2 {
+			return
+		}
+		sort.Strings(names)
+		node, _ := i.handler.Nodes.Get(nodeID)
+		nodes = append(nodes, crossroadNode{
+			id:       nodeID,
+			names:    names,
+			location: model.Location{Lat: node.Lat, Lon: node.Lon},
+		})
+	})
+	return nodes
+}
+
+// clusterCrossRoads merges nodes shared by the same set of streets which lie
+// within distance meters of each other, like the several nodes produced by
+// divided roads, into a single crossroad at their centroid.
+func clusterCrossRoads(nodes []crossroadNode, distance float64) []crossroad {
+	groups := make(map[string][]crossroadNode)
+	for _, n := range nodes {
+		key := strings.Join(n.names, "\n")
+		groups[key] = append(groups[key], n)
+	}
+	var result []crossroad
+	for _, group := range groups {
+		sort.Slice(group, func(a, b int) bool { return group[a].id < group[b].id })
+		var clusters [][]crossroadNode
+		for _, n := range group {
+			joined := []crossroadNode{n}
+			var rest [][]crossroadNode
+			for _, c := range clusters {
+				if isNear(c, n, distance) {
+					joined = append(joined, c...)
+				} else {
+					rest = append(rest, c)
 				}
-				meta := []byte(fmt.Sprintf(`{ "index" : { "_id" : "%s" } }%s`, nodeid, "\n"))
-				data = append(data, "\n"...)
-				buf.Grow(len(meta) + len(data))
-				buf.Write(meta)
-				buf.Write(data)
 			}
+			clusters = append(rest, joined)
+		}
+		for _, c := range clusters {
+			result = append(result, newCrossroad(c))
 		}
 	}
-	return buf, nil
+	sort.Slice(result, func(a, b int) bool { return result[a].nodeIDs[0] < result[b].nodeIDs[0] })
+	return result
+}
+
+// isNear reports whether any node of the cluster is within distance meters of n
+func isNear(cluster []crossroadNode, n crossroadNode, distance float64) bool {
+	point := geo.NewPoint(n.location.Lat, n.location.Lon)
+	for _, c := range cluster {
+		if point.GreatCircleDistance(geo.NewPoint(c.location.Lat, c.location.Lon))*1000 <= distance {
+			return true
+		}
+	}
+	return false
+}
+
+func newCrossroad(cluster []crossroadNode) crossroad {
+	cr := crossroad{names: cluster[0].names}
+	for _, n := range cluster {
+		cr.nodeIDs = append(cr.nodeIDs, n.id)
+		cr.location.Lat += n.location.Lat
+		cr.location.Lon += n.location.Lon
+	}
+	cr.location.Lat /= float64(len(cluster))
+	cr.location.Lon /= float64(len(cluster))
+	sort.Slice(cr.nodeIDs, func(a, b int) bool { return cr.nodeIDs[a] < cr.nodeIDs[b] })
+	return cr
 }
@@ -0,0 +1,32 @@
+package osm
+
+import (
+	"testing"
+
+	"github.com/maddevsio/ariadna/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClusterCrossRoads(t *testing.T) {
+	names := []string{"Советская", "Чуй"}
+	nodes := []crossroadNode{
+		// divided road junction, nodes about 20 meters apart
+		{id: 4, names: names, location: model.Location{Lat: 42.87640, Lon: 74.60560}},
+		{id: 2, names: names, location: model.Location{Lat: 42.87658, Lon: 74.60560}},
+		{id: 3, names: names, location: model.Location{Lat: 42.87649, Lon: 74.60580}},
+		// the same streets meet again far away
+		{id: 7, names: names, location: model.Location{Lat: 42.88649, Lon: 74.60580}},
+		// another street at the same place
+		{id: 5, names: []string{"Манаса", "Чуй"}, location: model.Location{Lat: 42.87640, Lon: 74.60560}},
+	}
+	crossroads := clusterCrossRoads(nodes, 50)
+	require.Len(t, crossroads, 3)
+	assert.Equal(t, []int64{2, 3, 4}, crossroads[0].nodeIDs)
+	assert.InDelta(t, 42.87649, crossroads[0].location.Lat, 1e-9)
+	assert.InDelta(t, 74.60566, crossroads[0].location.Lon, 1e-5)
+	assert.Equal(t, []int64{5}, crossroads[1].nodeIDs)
+	assert.Equal(t, []int64{7}, crossroads[2].nodeIDs)
+
+	assert.Len(t, clusterCrossRoads(nodes, 0), 5)
+}
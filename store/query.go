@@ -0,0 +1,94 @@
+package store
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/maddevsio/ariadna/model"
+)
+
+// SearchSize is the number of results of Search and Reverse
+const SearchSize = 10
+
+var (
+	// postcodeRe matches the six digit postal codes used across CIS countries
+	postcodeRe = regexp.MustCompile(`^\d{6}$`)
+	// intersectionRe splits "X и Y", "X x Y" and "X & Y" queries
+	intersectionRe = regexp.MustCompile(`(?i)\s+(?:и|x|х|&|and)\s+`)
+	// slashRe splits "X / Y" queries, see splitSlash
+	slashRe = regexp.MustCompile(`\s*/\s*`)
+	// intersectionPrefixRe matches leading words like "угол" in "угол X и Y"
+	intersectionPrefixRe = regexp.MustCompile(`(?i)^\s*(?:угол|пересечение)\s+`)
+)
+
+// streetPrefixes are street designations which are not indexed on crossroads
+var streetPrefixes = map[string]bool{
+	"улица": true, "ул": true, "ул.": true,
+	"проспект": true, "пр": true, "пр.": true, "пр-т": true,
+	"бульвар": true, "б-р": true,
+	"переулок": true, "пер": true, "пер.": true,
+}
+
+// SplitQuery splits a free form query into full text terms and postcodes.
+// Microdistrict designations like "мкр" are dropped since quarter names
+// are indexed without them.
+func SplitQuery(query string) (terms, postcodes []string) {
+	for _, token := range strings.Fields(query) {
+		if postcodeRe.MatchString(token) {
+			postcodes = append(postcodes, token)
+			continue
+		}
+		if model.IsQuarterPrefix(token) {
+			continue
+		}
+		terms = append(terms, token)
+	}
+	return terms, postcodes
+}
+
+// splitSlash splits the query at slashes except ones between digits, which
+// are house numbers like "Токтогула 125/1"
+func splitSlash(query string) []string {
+	var parts []string
+	last := 0
+	for _, m := range slashRe.FindAllStringIndex(query, -1) {
+		if m[0] > 0 && m[1] < len(query) && isDigit(query[m[0]-1]) && isDigit(query[m[1]]) {
+			continue
+		}
+		parts = append(parts, query[last:m[0]])
+		last = m[1]
+	}
+	return append(parts, query[last:])
+}
+
+func isDigit(b byte) bool {
+	return '0' <= b && b <= '9'
+}
+
+// ParseIntersection splits queries like "Чуй и Советская" into two street
+// names
+func ParseIntersection(query string) (string, string, bool) {
+	query = intersectionPrefixRe.ReplaceAllString(query, "")
+	var parts []string
+	for _, part := range splitSlash(query) {
+		parts = append(parts, intersectionRe.Split(part, -1)...)
+	}
+	if len(parts) != 2 {
+		return "", "", false
+	}
+	first, second := trimStreetPrefix(parts[0]), trimStreetPrefix(parts[1])
+	if first == "" || second == "" {
+		return "", "", false
+	}
+	return first, second, true
+}
+
+func trimStreetPrefix(street string) string {
+	var words []string
+	for _, word := range strings.Fields(street) {
+		if !streetPrefixes[strings.ToLower(word)] {
+			words = append(words, word)
+		}
+	}
+	return strings.Join(words, " ")
+}
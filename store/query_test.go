@@ -0,0 +1,26 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseIntersection(t *testing.T) {
+	cases := map[string][2]string{
+		"Чуй и Советская":           {"Чуй", "Советская"},
+		"Чуй / Советская":           {"Чуй", "Советская"},
+		"Чуй/Советская":             {"Чуй", "Советская"},
+		"Чуй x Советская":           {"Чуй", "Советская"},
+		"угол ул. Чуй и пр. Манаса": {"Чуй", "Манаса"},
+	}
+	for query, want := range cases {
+		first, second, ok := ParseIntersection(query)
+		assert.True(t, ok, query)
+		assert.Equal(t, want, [2]string{first, second}, query)
+	}
+	for _, query := range []string{"Киевская 5", "Токтогула 125/1", "Чуй 120 / 3"} {
+		_, _, ok := ParseIntersection(query)
+		assert.False(t, ok, query)
+	}
+}
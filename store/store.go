@@ -0,0 +1,134 @@
+// Package store defines where the importer writes documents and where the
+// API searches them. elastic and embedded packages implement it.
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/maddevsio/ariadna/model"
+)
+
+// Sink receives documents built by the importer. Every import writes into
+// a new generation of the index which is served once SwapAlias is called.
+type Sink interface {
+	// CreateIndex creates a new generation for BulkWrite, meta is stored
+	// with it
+	CreateIndex(ctx context.Context, meta Meta) error
+	// CreatedIndex returns the name of the created generation
+	CreatedIndex() string
+	// BulkWrite writes documents to the created generation
+	BulkWrite(ctx context.Context, docs []Document) error
+	// BulkUpdate writes and deletes documents of the served generation
+	BulkUpdate(ctx context.Context, docs []Document) error
+	// SwapAlias starts serving the created generation
+	SwapAlias(ctx context.Context) error
+	// DeleteCreatedIndex drops the created generation of a failed import
+	DeleteCreatedIndex(ctx context.Context) error
+	// Prune deletes generations which are not served except for the
+	// created one and keep most recent ones. It returns deleted names.
+	Prune(ctx context.Context, keep int) ([]string, error)
+}
+
+// Document is a document written to a sink, a nil Address deletes the
+// document with the ID
+type Document struct {
+	ID      string
+	Address *model.Address
+}
+
+// Searcher looks up documents in the served generation
+type Searcher interface {
+	// Search returns addresses matching the free form query
+	Search(ctx context.Context, query string) ([]model.Address, error)
+	// Reverse returns addresses nearest to the point
+	Reverse(ctx context.Context, lat, lon float64) ([]model.Address, error)
+	// Places returns places of the category around the point, nearest first
+	Places(ctx context.Context, q PlacesQuery) ([]model.Address, error)
+}
+
+// Store is a complete backend for importing and serving
+type Store interface {
+	Sink
+	Searcher
+	// Indices returns generations, oldest first
+	Indices(ctx context.Context) ([]Index, error)
+	// Rollback serves the generation preceding the current one and
+	// returns its name
+	Rollback(ctx context.Context) (string, error)
+	// Status describes the served generation, it fails if the store is
+	// unreachable
+	Status(ctx context.Context) (Status, error)
+	// SetMeta replaces the meta of the served generation, it is used after
+	// a diff is applied
+	SetMeta(ctx context.Context, meta Meta) error
+	// Close releases resources held by the store
+	Close() error
+}
+
+// Index is a generation of the index created by an import
+type Index struct {
+	Name string `json:"name"`
+	// Aliased is true if the generation is served
+	Aliased bool `json:"aliased"`
+}
+
+// Meta describes the import which created a generation
+type Meta struct {
+	// Imported is the time the import created the generation
+	Imported *time.Time `json:"imported,omitempty"`
+	// OSMTimestamp is the replication timestamp of the extract or of the
+	// last applied diff
+	OSMTimestamp *time.Time `json:"osm_timestamp,omitempty"`
+	// Updated is the time a diff was last applied
+	Updated *time.Time `json:"updated,omitempty"`
+}
+
+// Status describes the served generation
+type Status struct {
+	// Index is the served generation, empty if there is none
+	Index     string `json:"index"`
+	Documents uint64 `json:"documents"`
+	Meta
+}
+
+// PlacesQuery describes a category search around a point
+type PlacesQuery struct {
+	Category string
+	Lat      float64
+	Lon      float64
+	// Radius is a search radius in meters
+	Radius float64
+	From   int
+	Size   int
+}
+
+// Previous returns the generation preceding the served one
+func Previous(indices []Index) (string, error) {
+	current := -1
+	for i, index := range indices {
+		if index.Aliased {
+			current = i
+		}
+	}
+	if current < 1 {
+		return "", errors.New("no previous index to roll back to")
+	}
+	return indices[current-1].Name, nil
+}
+
+// Prunable returns generations Prune deletes: the ones not served, except
+// for created and keep most recent ones
+func Prunable(indices []Index, created string, keep int) []string {
+	var candidates []string
+	for _, index := range indices {
+		if !index.Aliased && index.Name != created {
+			candidates = append(candidates, index.Name)
+		}
+	}
+	if len(candidates) <= keep {
+		return nil
+	}
+	return candidates[:len(candidates)-keep]
+}